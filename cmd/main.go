@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/db"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/geoip"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/iplist"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/mail"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/redis"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/router"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/server"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/sms"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -42,11 +48,36 @@ func main() {
 		AppName: "auth-service",
 	})
 
-	sessionRepo := repositories.NewSessionRepository(redisClient)
+	geo, err := geoip.Open(cfg.GeoIP.CityDB, cfg.GeoIP.ASNDB)
+	if err != nil {
+		log.Printf("geoip not loaded, retrying on reload: %v", err)
+	}
+	go geo.Watch(context.Background(), cfg.GeoIP.ReloadInterval)
+
+	sessionRepo := repositories.NewSessionRepository(redisClient, geo)
 	rateLimiter := security.NewRateLimiter(redisClient)
-	AuditRepo := repositories.NewAuditRepo(dbConn)
+	AuditRepo := repositories.NewAuditRepo(dbConn, geo)
 	passwordResetRepo := repositories.NewResetPasswordRepository(redisClient)
-	router.Register(app, dbConn, cfg.JWT, sessionRepo, rateLimiter, AuditRepo, passwordResetRepo)
+	permissionRepo := repositories.NewPermissionRepository(dbConn, redisClient)
+
+	var smsSender services.SMSSender = sms.NewLogSender(cfg.SMS.LogFile)
+	if cfg.SMS.Driver == "http" {
+		smsSender = sms.NewHTTPSender(cfg.SMS.HTTPURL, cfg.SMS.HTTPToken, cfg.SMS.From)
+	}
+
+	var mailer services.Mailer = mail.NewLogMailer(cfg.Mail.LogFile)
+	if cfg.Mail.Driver == "smtp" {
+		mailer = mail.NewSMTPMailer(cfg.Mail.SMTPAddr, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	}
+
+	ipBlocklist, err := iplist.Load(cfg.Risk.IPBlocklist)
+	if err != nil {
+		log.Printf("ip reputation list disabled: %v", err)
+	} else if ipBlocklist != nil {
+		log.Printf("ip reputation list loaded: %d entries", ipBlocklist.Len())
+	}
+
+	router.Register(app, dbConn, cfg.JWT, sessionRepo, rateLimiter, AuditRepo, passwordResetRepo, permissionRepo, cfg.Session, cfg.Account, smsSender, mailer, cfg.PublicURL, cfg.FrontendURL, cfg.Risk, ipBlocklist)
 	server.Start(app, cfg.AppPort)
 
 }
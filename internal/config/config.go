@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,10 +17,86 @@ type DBConfig struct {
 }
 
 type JWTConfig struct {
-	AccessSecret  string
-	RefreshSecret string
-	AccessTTL     time.Duration
-	RefreshTTL    time.Duration
+	AccessSecret     string
+	RefreshSecret    string
+	AccessTTL        time.Duration
+	RefreshTTL       time.Duration
+	ImpersonationTTL time.Duration
+	// TrustedDeviceTTL is how long a remembered device skips the second
+	// factor; 0 turns remembering off.
+	TrustedDeviceTTL time.Duration
+	// ProfileClaims lists the profile fields copied into access tokens
+	// (any of name, locale, zoneinfo, picture).
+	ProfileClaims []string
+}
+
+// SessionConfig limits refresh sessions. Zero disables a limit.
+type SessionConfig struct {
+	// IdleTimeout ends a session that has not been refreshed for this long.
+	IdleTimeout time.Duration
+	// MaxLifetime ends a session this long after login, however often it
+	// is refreshed.
+	MaxLifetime time.Duration
+	// MaxPerUser and MaxPerAdmin cap concurrent sessions per account.
+	MaxPerUser  int
+	MaxPerAdmin int
+	// LimitPolicy is "evict_oldest" (end the oldest session to make room)
+	// or "reject" (refuse the new login) when a cap is reached.
+	LimitPolicy string
+	// BindingPolicy is what Refresh does when the client's User-Agent
+	// family or IP subnet differs from the session's: "off", "flag" (audit
+	// and carry on) or "reject" (audit and end the session).
+	BindingPolicy string
+	// BindingIPv4Prefix and BindingIPv6Prefix size the subnet a session's
+	// IP may move within.
+	BindingIPv4Prefix int
+	BindingIPv6Prefix int
+}
+
+type AccountConfig struct {
+	DeletionGrace         time.Duration
+	DeletionMode          string
+	DeletionSweepInterval time.Duration
+}
+
+type SMSConfig struct {
+	// Driver is "log" (write to LogFile or stdout) or "http".
+	Driver    string
+	LogFile   string
+	HTTPURL   string
+	HTTPToken string
+	From      string
+}
+
+type MailConfig struct {
+	// Driver is "log" (write to LogFile or stdout) or "smtp".
+	Driver       string
+	LogFile      string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+}
+
+// GeoIPConfig points at MaxMind-format databases; empty paths disable the
+// lookup.
+type GeoIPConfig struct {
+	CityDB         string
+	ASNDB          string
+	ReloadInterval time.Duration
+}
+
+// RiskConfig tunes login risk scoring. A threshold of 0 disables that
+// outcome.
+type RiskConfig struct {
+	// MFAThreshold is the score from which a login must pass an SMS code.
+	MFAThreshold int
+	// BlockThreshold is the score from which a login is refused.
+	BlockThreshold int
+	// MaxTravelSpeed in km/h; faster travel between logins is impossible.
+	MaxTravelSpeed float64
+	// IPBlocklist is a file of IPs and CIDR ranges with bad reputation.
+	IPBlocklist string
 }
 
 type RedisConfig struct {
@@ -29,10 +106,23 @@ type RedisConfig struct {
 }
 
 type Config struct {
-	AppPort  string
+	AppPort string
+	// PublicURL is the externally reachable base URL of the service, used
+	// for links in emails.
+	PublicURL string
+	// FrontendURL is the base URL of the app that hosts the pages emails
+	// send users to, e.g. to accept an invitation or set a password.
+	FrontendURL string
+
 	DB       DBConfig
 	RedisURL RedisConfig
 	JWT      JWTConfig
+	Session  SessionConfig
+	Account  AccountConfig
+	SMS      SMSConfig
+	Mail     MailConfig
+	GeoIP    GeoIPConfig
+	Risk     RiskConfig
 }
 
 func Load() *Config {
@@ -40,6 +130,8 @@ func Load() *Config {
 
 	// LOAD APP ENV
 	cfg.AppPort = getEnv("APP_PORT", "8080")
+	cfg.PublicURL = getEnv("APP_PUBLIC_URL", "http://localhost:"+cfg.AppPort)
+	cfg.FrontendURL = getEnv("APP_FRONTEND_URL", cfg.PublicURL)
 
 	// LOAD DB ENV
 	cfg.DB.URL = mustGetEnv("DB_URL")
@@ -58,6 +150,50 @@ func Load() *Config {
 	cfg.JWT.RefreshSecret = mustGetEnv("JWT_REFRESH_SECRET")
 	cfg.JWT.AccessTTL = mustGetEnvDuration("ACCESS_TOKEN_TTL")
 	cfg.JWT.RefreshTTL = mustGetEnvDuration("REFRESH_TOKEN_TTL")
+	cfg.JWT.ImpersonationTTL = getEnvDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute)
+	cfg.JWT.TrustedDeviceTTL = getEnvDuration("TRUSTED_DEVICE_TTL", 30*24*time.Hour)
+	cfg.JWT.ProfileClaims = getEnvList("TOKEN_PROFILE_CLAIMS")
+
+	// LOAD SESSION ENV
+	cfg.Session.IdleTimeout = getEnvDuration("SESSION_IDLE_TIMEOUT", 7*24*time.Hour)
+	cfg.Session.MaxLifetime = getEnvDuration("SESSION_MAX_LIFETIME", 30*24*time.Hour)
+	cfg.Session.MaxPerUser = getEnvInt("SESSION_MAX_PER_USER", 10)
+	cfg.Session.MaxPerAdmin = getEnvInt("SESSION_MAX_PER_ADMIN", 3)
+	cfg.Session.LimitPolicy = getEnv("SESSION_LIMIT_POLICY", "evict_oldest")
+	cfg.Session.BindingPolicy = getEnv("SESSION_BINDING_POLICY", "off")
+	cfg.Session.BindingIPv4Prefix = getEnvInt("SESSION_BINDING_IPV4_PREFIX", 24)
+	cfg.Session.BindingIPv6Prefix = getEnvInt("SESSION_BINDING_IPV6_PREFIX", 64)
+
+	// LOAD ACCOUNT ENV
+	cfg.Account.DeletionGrace = getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+	cfg.Account.DeletionMode = getEnv("ACCOUNT_DELETION_MODE", "delete")
+	cfg.Account.DeletionSweepInterval = getEnvDuration("ACCOUNT_DELETION_SWEEP_INTERVAL", time.Hour)
+
+	// LOAD SMS ENV
+	cfg.SMS.Driver = getEnv("SMS_DRIVER", "log")
+	cfg.SMS.LogFile = getEnv("SMS_LOG_FILE", "")
+	cfg.SMS.HTTPURL = getEnv("SMS_HTTP_URL", "")
+	cfg.SMS.HTTPToken = getEnv("SMS_HTTP_TOKEN", "")
+	cfg.SMS.From = getEnv("SMS_FROM", "")
+
+	// LOAD MAIL ENV
+	cfg.Mail.Driver = getEnv("MAIL_DRIVER", "log")
+	cfg.Mail.LogFile = getEnv("MAIL_LOG_FILE", "")
+	cfg.Mail.SMTPAddr = getEnv("SMTP_ADDR", "")
+	cfg.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	cfg.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	cfg.Mail.From = getEnv("MAIL_FROM", "")
+
+	// LOAD GEOIP ENV
+	cfg.GeoIP.CityDB = getEnv("GEOIP_CITY_DB", "")
+	cfg.GeoIP.ASNDB = getEnv("GEOIP_ASN_DB", "")
+	cfg.GeoIP.ReloadInterval = getEnvDuration("GEOIP_RELOAD_INTERVAL", time.Minute)
+
+	// LOAD RISK ENV
+	cfg.Risk.MFAThreshold = getEnvInt("RISK_MFA_THRESHOLD", 50)
+	cfg.Risk.BlockThreshold = getEnvInt("RISK_BLOCK_THRESHOLD", 90)
+	cfg.Risk.MaxTravelSpeed = float64(getEnvInt("RISK_MAX_TRAVEL_SPEED", 1000))
+	cfg.Risk.IPBlocklist = getEnv("RISK_IP_BLOCKLIST", "")
 
 	return cfg
 }
@@ -78,6 +214,16 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func mustGetEnvDuration(key string) time.Duration {
 	val := os.Getenv(key)
 
@@ -94,6 +240,21 @@ func mustGetEnvDuration(key string) time.Duration {
 	return d
 }
 
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("invalid duration value for env %s: %v", key, err)
+		return defaultVal
+	}
+
+	return d
+}
+
 func getEnvInt(key string, defaultVal int) int {
 	valStr := os.Getenv(key)
 	if valStr == "" {
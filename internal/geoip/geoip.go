@@ -0,0 +1,201 @@
+// Package geoip looks up the location of IP addresses in locally mounted
+// MaxMind-format databases (GeoLite2/GeoIP2 City and ASN).
+package geoip
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type Location struct {
+	Country     string `json:"country,omitempty"`
+	CountryName string `json:"country_name,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+	// Latitude and Longitude locate the city, or the country's center; both
+	// are 0 when unknown. They are kept out of API responses.
+	Latitude  float64 `json:"-"`
+	Longitude float64 `json:"-"`
+}
+
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// database is one .mmdb file, reopened when it changes on disk.
+type database struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	// lastErr is the last reload error logged by Watch, so a missing file
+	// is reported once rather than on every check.
+	lastErr string
+}
+
+func (d *database) load() error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.RLock()
+	unchanged := d.reader != nil && info.ModTime().Equal(d.modTime)
+	d.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	reader, err := maxminddb.Open(d.path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	old := d.reader
+	d.reader = reader
+	d.modTime = info.ModTime()
+	d.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+func (d *database) lookup(ip net.IP, result any) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.reader == nil {
+		return false
+	}
+	return d.reader.Lookup(ip, result) == nil
+}
+
+// Resolver looks up locations. A nil *Resolver is valid and finds nothing,
+// so GeoIP stays optional.
+type Resolver struct {
+	city *database
+	asn  *database
+}
+
+// Open loads the City and ASN databases; either path may be empty to skip
+// that database. It returns nil if both are empty. A database that fails to
+// load is reported in the error but the Resolver is still returned, finding
+// nothing in it until Watch manages to load the file.
+func Open(cityPath, asnPath string) (*Resolver, error) {
+	if cityPath == "" && asnPath == "" {
+		return nil, nil
+	}
+
+	r := &Resolver{}
+	var errs []error
+	if cityPath != "" {
+		r.city = &database{path: cityPath}
+		if err := r.city.load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if asnPath != "" {
+		r.asn = &database{path: asnPath}
+		if err := r.asn.load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return r, errors.Join(errs...)
+}
+
+// Watch reloads a database whenever its file changes, checking every
+// interval until ctx is done. Databases missing at startup are loaded as
+// soon as their file appears. Replace database files atomically (write a
+// temp file, then rename) so a reload never sees a partial file.
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	if r == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, db := range []*database{r.city, r.asn} {
+			if db == nil {
+				continue
+			}
+			err := db.load()
+			switch {
+			case err != nil && err.Error() != db.lastErr:
+				log.Printf("geoip reload of %s failed: %v", db.path, err)
+				db.lastErr = err.Error()
+			case err == nil && db.lastErr != "":
+				log.Printf("geoip loaded %s", db.path)
+				db.lastErr = ""
+			}
+		}
+	}
+}
+
+// Lookup returns what the databases know about ip. Private and loopback
+// addresses, and anything unparsable, give an empty Location.
+func (r *Resolver) Lookup(ip string) Location {
+	var loc Location
+	if r == nil {
+		return loc
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsLoopback() || parsed.IsPrivate() || parsed.IsUnspecified() {
+		return loc
+	}
+
+	if r.city != nil {
+		var rec cityRecord
+		if r.city.lookup(parsed, &rec) {
+			loc.Country = rec.Country.ISOCode
+			loc.CountryName = rec.Country.Names["en"]
+			loc.City = rec.City.Names["en"]
+			loc.Latitude = rec.Location.Latitude
+			loc.Longitude = rec.Location.Longitude
+		}
+	}
+
+	if r.asn != nil {
+		var rec asnRecord
+		if r.asn.lookup(parsed, &rec) {
+			loc.ASN = rec.Number
+			loc.ASOrg = rec.Organization
+		}
+	}
+
+	return loc
+}
@@ -0,0 +1,29 @@
+package geoip
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMissingFileKeepsResolver(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := Open(filepath.Join(dir, "GeoLite2-City.mmdb"), filepath.Join(dir, "GeoLite2-ASN.mmdb"))
+	if err == nil {
+		t.Fatal("Open with missing files returned no error")
+	}
+	if r == nil {
+		t.Fatal("Open with missing files returned a nil Resolver, so Watch could never load them")
+	}
+
+	if loc := r.Lookup("8.8.8.8"); loc != (Location{}) {
+		t.Errorf("Lookup before load = %+v, want empty", loc)
+	}
+}
+
+func TestOpenNoPaths(t *testing.T) {
+	r, err := Open("", "")
+	if err != nil || r != nil {
+		t.Errorf("Open(\"\", \"\") = %v, %v; want nil, nil", r, err)
+	}
+}
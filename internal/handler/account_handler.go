@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountHandler struct {
+	deletionService *services.AccountDeletionService
+	exportService   *services.DataExportService
+}
+
+func NewAccountHandler(ads *services.AccountDeletionService, des *services.DataExportService) *AccountHandler {
+	return &AccountHandler{deletionService: ads, exportService: des}
+}
+
+func (h *AccountHandler) RequestDeletion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	scheduledFor, err := h.deletionService.RequestDeletion(userID, req.Password, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.Status(401).JSON(fiber.Map{"error": "invalid password"})
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(409).JSON(fiber.Map{"error": "account cannot be deleted in its current state"})
+		case errors.Is(err, services.ErrLastOwner):
+			return c.Status(409).JSON(fiber.Map{"error": "transfer ownership of your organizations first"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	clearAuthCookies(c)
+
+	return c.Status(202).JSON(fiber.Map{
+		"message":       "account scheduled for deletion",
+		"scheduled_for": scheduledFor,
+	})
+}
+
+func (h *AccountHandler) CancelDeletion(c *fiber.Ctx) error {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.deletionService.CancelDeletion(req.Email, req.Password, c.IP(), c.Get("User-Agent")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "email and password are required"})
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.Status(401).JSON(fiber.Map{"error": "invalid email or password"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(409).JSON(fiber.Map{"error": "account is not scheduled for deletion"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "account deletion cancelled",
+	})
+}
+
+func (h *AccountHandler) RequestExport(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.exportService.RequestExport(userID, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, services.ErrExportThrottled) {
+			return c.Status(429).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.Status(202).JSON(fiber.Map{
+		"message": "export started; a download link is sent when it is ready",
+		"export":  export,
+	})
+}
+
+func (h *AccountHandler) GetExport(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.exportService.GetExport(userID, c.Params("exportID"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "export not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"export": export,
+	})
+}
+
+func (h *AccountHandler) DownloadExport(c *fiber.Ctx) error {
+	export, archive, err := h.exportService.Download(c.Query("token"), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "token is required"})
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.Status(404).JSON(fiber.Map{"error": "invalid or expired download token"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="data-export-`+export.ID+`.json"`)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Send(archive)
+}
+
+// clearAuthCookies expires the refresh and CSRF cookies, as on logout.
+func clearAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     "/auth",
+		MaxAge:   -1,
+		HTTPOnly: true,
+		Secure:   isProd(),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "csrf_token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   isProd(),
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	adminService *services.AdminService
+}
+
+func NewAdminHandler(ads *services.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: ads}
+}
+
+func adminError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return c.Status(400).JSON(fiber.Map{"error": "invalid input"})
+	case errors.Is(err, services.ErrSelfAction):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
+
+func targetUserID(c *fiber.Ctx) (uint, bool) {
+	id, err := c.ParamsInt("userID")
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	user, err := h.adminService.GetUser(userID)
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+func (h *AdminHandler) UpdateRole(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.adminService.UpdateRole(adminID, userID, models.UserRole(req.Role), c.IP(), c.Get("User-Agent")); err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "role updated",
+	})
+}
+
+// SetAppMetadata takes the new app_metadata object as the request body.
+func (h *AdminHandler) SetAppMetadata(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	user, err := h.adminService.SetAppMetadata(adminID, userID, c.Body(), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return c.Status(400).JSON(fiber.Map{"error": "app_metadata must be a JSON object of at most 16KB"})
+		}
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"app_metadata": user.AppMetadata,
+	})
+}
+
+func (h *AdminHandler) Suspend(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&req)
+
+	if err := h.adminService.Suspend(adminID, userID, req.Reason, c.IP(), c.Get("User-Agent")); err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "user suspended",
+	})
+}
+
+func (h *AdminHandler) Unsuspend(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	if err := h.adminService.Unsuspend(adminID, userID, c.IP(), c.Get("User-Agent")); err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "user unsuspended",
+	})
+}
+
+func (h *AdminHandler) ForcePasswordReset(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	if err := h.adminService.ForcePasswordReset(adminID, userID, c.IP(), c.Get("User-Agent")); err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "password reset required on next login",
+	})
+}
+
+func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := targetUserID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	if err := h.adminService.DeleteUser(adminID, userID, c.IP(), c.Get("User-Agent")); err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "user deleted",
+	})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(aks *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: aks}
+}
+
+func (h *APIKeyHandler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	orgID := c.Locals("org_id").(uint)
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	key, rowKey, err := h.apiKeyService.Create(
+		userID,
+		orgID,
+		req.Name,
+		req.Scopes,
+		time.Duration(req.ExpiresInDays)*24*time.Hour,
+		c.IP(),
+		c.Get("User-Agent"),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{
+				"error":        "invalid name, scopes or expiry",
+				"valid_scopes": services.APIKeyScopes,
+			})
+		case errors.Is(err, services.ErrForbidden):
+			return c.Status(409).JSON(fiber.Map{"error": "api key limit reached"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	// the raw key is only ever returned here
+	return c.Status(201).JSON(fiber.Map{
+		"api_key": key,
+		"key":     rowKey,
+	})
+}
+
+func (h *APIKeyHandler) List(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	keys, err := h.apiKeyService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "failed to fetch api keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+func (h *APIKeyHandler) Revoke(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	keyID, err := c.ParamsInt("keyID")
+	if err != nil || keyID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid keyID"})
+	}
+
+	if err := h.apiKeyService.Revoke(userID, uint(keyID), c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "api key not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "api key revoked",
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/mail"
 	"os"
+	"time"
 
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
@@ -24,7 +26,14 @@ func isProd() bool {
 type userRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
+}
+
+// loginRequest takes an email, username or phone number as identifier;
+// email is still accepted on its own for older clients.
+type loginRequest struct {
+	Identifier string `json:"identifier"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
@@ -42,7 +51,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.authService.Register(req.Email, req.Password, req.Role)
+	err := h.authService.Register(req.Email, req.Password)
 
 	if err != nil {
 		return c.Status(405).JSON(fiber.Map{
@@ -57,7 +66,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
-	var req userRequest
+	var req loginRequest
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
@@ -68,19 +77,48 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	ip := c.IP()
 	ua := c.Get("User-Agent")
 
-	tokens, err := h.authService.Login(req.Email, req.Password, ip, ua)
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	tokens, err := h.authService.Login(identifier, req.Password, c.Cookies("trusted_device"), ip, ua)
 
 	if err != nil {
+		var mfaErr *services.MFARequiredError
 		switch {
+		case errors.As(err, &mfaErr):
+			return c.Status(200).JSON(fiber.Map{
+				"mfa_required": true,
+				"mfa_token":    mfaErr.Token,
+				"methods":      mfaErr.Methods,
+				"expires_in":   mfaErr.ExpiresIn,
+			})
 		case errors.Is(err, services.ErrInvalidInput):
 			return c.Status(400).JSON(fiber.Map{"error": "invalid input"})
 		case errors.Is(err, services.ErrInvalidCredentials):
-			return c.Status(401).JSON(fiber.Map{"error": "invalid email or password"})
+			return c.Status(401).JSON(fiber.Map{"error": "invalid credentials"})
+		case errors.Is(err, services.ErrAccountSuspended):
+			return c.Status(403).JSON(fiber.Map{"error": "account suspended"})
+		case errors.Is(err, services.ErrMustResetPassword):
+			return c.Status(403).JSON(fiber.Map{"error": "password reset required"})
+		case errors.Is(err, services.ErrPendingDeletion):
+			return c.Status(403).JSON(fiber.Map{"error": "account scheduled for deletion; cancel it to log in again"})
+		case errors.Is(err, services.ErrSessionLimit):
+			return c.Status(409).JSON(fiber.Map{"error": "too many active sessions; log out elsewhere first"})
+		case errors.Is(err, services.ErrLoginBlocked):
+			return c.Status(403).JSON(fiber.Map{"error": "login blocked for security reasons"})
 		default:
 			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
 		}
 	}
 
+	return loginResponse(c, tokens)
+}
+
+// loginResponse sets the refresh and CSRF cookies for a new session and
+// returns the access token.
+func loginResponse(c *fiber.Ctx, tokens *services.TokenPair) error {
 	c.Cookie(&fiber.Cookie{
 		Name:     "refresh_token",
 		Value:    tokens.RefreshToken,
@@ -91,6 +129,19 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		MaxAge:   int(tokens.RefreshTTL.Seconds()),
 	})
 
+	// only login needs the trusted device cookie
+	if tokens.TrustedDeviceToken != "" {
+		c.Cookie(&fiber.Cookie{
+			Name:     "trusted_device",
+			Value:    tokens.TrustedDeviceToken,
+			HTTPOnly: true,
+			Secure:   true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+			Path:     "/auth/login",
+			MaxAge:   int(tokens.TrustedDeviceTTL.Seconds()),
+		})
+	}
+
 	csrfToken, _ := services.GenerateCSRFToken()
 	c.Cookie(&fiber.Cookie{
 		Name:     "csrf_token",
@@ -121,6 +172,12 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 
 	tokens, err := h.authService.Refresh(refreshToken, ip, ua)
 	if err != nil {
+		if errors.Is(err, services.ErrSessionExpired) {
+			return c.Status(401).JSON(fiber.Map{"error": "session expired; log in again"})
+		}
+		if errors.Is(err, services.ErrSessionBinding) {
+			return c.Status(401).JSON(fiber.Map{"error": "session used from a different device or network; log in again"})
+		}
 		return c.Status(401).JSON(fiber.Map{"error": "invalid refresh token"})
 	}
 
@@ -142,29 +199,92 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 
 }
 
-func (h *AuthHandler) UserList(c *fiber.Ctx) error {
-	users, err := h.authService.GetAllUsers()
+// userFilter builds a listing filter from the query string. The handlers
+// decide the organization scope.
+func userFilter(c *fiber.Ctx) (repositories.UserFilter, error) {
+	filter := repositories.UserFilter{
+		Role:        c.Query("role"),
+		Status:      c.Query("status"),
+		EmailPrefix: c.Query("email_prefix"),
+		Sort:        c.Query("sort"),
+		Limit:       c.QueryInt("limit"),
+		Cursor:      c.Query("cursor"),
+	}
+
+	switch c.Query("order", "asc") {
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, services.ErrInvalidInput
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, services.ErrInvalidInput
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *AuthHandler) listUsers(c *fiber.Ctx, list func(repositories.UserFilter) (*repositories.UserPage, error)) error {
+	filter, err := userFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid query parameters",
+		})
+	}
+
+	page, err := list(filter)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid sort or cursor",
+			})
+		}
+		if errors.Is(err, services.ErrForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "no active organization",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "failed to fetch admin users",
+			"message": "failed to fetch users",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"admin_lists": users,
+		"admin_lists": page.Users,
+		"next_cursor": page.NextCursor,
+		"total":       page.Total,
+	})
+}
+
+// UserList lists the members of the caller's active organization.
+func (h *AuthHandler) UserList(c *fiber.Ctx) error {
+	callerID := c.Locals("user_id").(uint)
+	orgID := c.Locals("org_id").(uint)
+
+	return h.listUsers(c, func(filter repositories.UserFilter) (*repositories.UserPage, error) {
+		return h.authService.ListOrgUsers(callerID, orgID, filter)
 	})
 }
 
+// AdminUserList lists admins of every organization, or of one with
+// ?organization_id=.
 func (h *AuthHandler) AdminUserList(c *fiber.Ctx) error {
-	admins, err := h.authService.GetAllAdmins()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "failed to fetch admin users",
-		})
-	}
+	orgID := uint(c.QueryInt("organization_id"))
 
-	return c.JSON(fiber.Map{
-		"admin_lists": admins,
+	return h.listUsers(c, func(filter repositories.UserFilter) (*repositories.UserPage, error) {
+		filter.OrgID = orgID
+		return h.authService.ListAdmins(filter)
 	})
 }
 
@@ -211,19 +331,146 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 
 }
 
+func (h *AuthHandler) SwitchOrg(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		OrgID uint `json:"org_id"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.OrgID == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "org_id required",
+		})
+	}
+
+	tokens, err := h.authService.SwitchOrganization(
+		userID,
+		req.OrgID,
+		c.Cookies("refresh_token"),
+		c.IP(),
+		c.Get("User-Agent"),
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			return c.Status(403).JSON(fiber.Map{"error": "not a member of this organization"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token": tokens.AccessToken,
+		"expires_in":   tokens.ExpiresIn,
+	})
+}
+
+func (h *AuthHandler) Reauthenticate(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	tokens, err := h.authService.Reauthenticate(userID, req.Password, c.Cookies("refresh_token"), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "password is required"})
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.Status(401).JSON(fiber.Map{"error": "invalid password"})
+		case errors.Is(err, services.ErrAccountSuspended):
+			return c.Status(403).JSON(fiber.Map{"error": "account suspended"})
+		case errors.Is(err, services.ErrMustResetPassword):
+			return c.Status(403).JSON(fiber.Map{"error": "password reset required"})
+		case errors.Is(err, services.ErrPendingDeletion):
+			return c.Status(403).JSON(fiber.Map{"error": "account scheduled for deletion"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token": tokens.AccessToken,
+		"expires_in":   tokens.ExpiresIn,
+	})
+}
+
 func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	sessions, err := h.authService.ListSessions(userID)
+	sessions, err := h.authService.ListSessions(userID, c.Cookies("refresh_token"), c.Query("sort"), c.Query("order") == "asc")
 
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "sort must be last_active_at, started_at or created_at",
+			})
+		}
 		return c.Status(500).JSON(fiber.Map{
 			"error": "failed to fetch session",
 		})
 	}
 
+	trustedDevices, err := h.authService.ListTrustedDevices(userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "failed to fetch trusted devices",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions":        sessions,
+		"trusted_devices": trustedDevices,
+	})
+}
+
+func (h *AuthHandler) RevokeTrustedDevice(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.authService.RevokeTrustedDevice(userID, c.Params("deviceID"), c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "trusted device not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "trusted device revoked",
+	})
+}
+
+func (h *AuthHandler) RenameSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.authService.RenameSession(userID, c.Params("sessionID"), req.Name, c.IP(), c.Get("User-Agent")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "name must be at most 64 characters"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "session not found"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
 	return c.JSON(fiber.Map{
-		"sessions": sessions,
+		"message": "session renamed",
 	})
 }
 
@@ -290,6 +537,17 @@ func (h *AuthHandler) LogoutAllSession(c *fiber.Ctx) error {
 		SameSite: fiber.CookieSameSiteStrictMode,
 	})
 
+	// Trusted device, revoked server-side as well
+	c.Cookie(&fiber.Cookie{
+		Name:     "trusted_device",
+		Value:    "",
+		Path:     "/auth/login",
+		MaxAge:   -1,
+		HTTPOnly: true,
+		Secure:   isProd(),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
 	return c.Status(200).JSON(fiber.Map{
 		"message": "logout all sessions",
 	})
@@ -352,3 +610,55 @@ func (h *AuthHandler) PasswordResetConfirm(c *fiber.Ctx) error {
 		"message": "password reset successful",
 	})
 }
+
+func (h *AuthHandler) StartImpersonation(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+
+	targetID, err := c.ParamsInt("userID")
+	if err != nil || targetID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	imp, err := h.authService.StartImpersonation(adminID, uint(targetID), req.Reason, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "reason required and target must be another user"})
+		case errors.Is(err, services.ErrForbidden):
+			return c.Status(403).JSON(fiber.Map{"error": "cannot impersonate this user"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	return c.JSON(imp)
+}
+
+func (h *AuthHandler) StopImpersonation(c *fiber.Ctx) error {
+	targetID := c.Locals("user_id").(uint)
+	adminID := c.Locals("impersonator_id").(uint)
+	impersonationID := c.Locals("impersonation_id").(string)
+
+	if err := h.authService.StopImpersonation(impersonationID, adminID, targetID, c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "impersonation already ended"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "impersonation ended",
+	})
+}
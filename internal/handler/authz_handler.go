@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuthzHandler struct {
+	authzService *services.AuthzService
+}
+
+func NewAuthzHandler(azs *services.AuthzService) *AuthzHandler {
+	return &AuthzHandler{authzService: azs}
+}
+
+func (h *AuthzHandler) Check(c *fiber.Ctx) error {
+	var req services.AuthzCheck
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	decision, err := h.authzService.Check(req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "authorization check failed",
+		})
+	}
+
+	return c.JSON(decision)
+}
+
+func (h *AuthzHandler) CheckBatch(c *fiber.Ctx) error {
+	var req struct {
+		Checks []services.AuthzCheck `json:"checks"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	decisions, err := h.authzService.CheckBatch(req.Checks, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "checks must contain between 1 and 100 entries",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "authorization check failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"decisions": decisions,
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LoginAlertHandler struct {
+	alertService *services.LoginAlertService
+}
+
+func NewLoginAlertHandler(las *services.LoginAlertService) *LoginAlertHandler {
+	return &LoginAlertHandler{alertService: las}
+}
+
+// reportPage is shown by the "this wasn't me" link. Opening the link only
+// shows the form, so mail scanners and link previews that fetch it do not
+// sign the user out; the report happens when the user submits it.
+var reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Report a sign-in</title>
+</head>
+<body>
+{{if .Token}}
+<h1>Wasn't you?</h1>
+<p>Reporting this sign-in signs your account out everywhere. You will need to reset your password before you can sign in again; a reset link is emailed to you.</p>
+<form method="post" action="/auth/login-alerts/report">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Sign out everywhere and reset my password</button>
+</form>
+{{else}}
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+type reportPageData struct {
+	Token   string
+	Title   string
+	Message string
+}
+
+func renderReportPage(c *fiber.Ctx, status int, data reportPageData) error {
+	var buf bytes.Buffer
+	if err := reportPage.Execute(&buf, data); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	// the token is in the URL and the button is destructive: keep the page
+	// out of caches, referrers and other sites' frames
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	c.Set(fiber.HeaderXFrameOptions, "DENY")
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// ConfirmReport is the "this wasn't me" link from a login alert email. It
+// changes nothing and asks the user to confirm.
+func (h *LoginAlertHandler) ConfirmReport(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return renderReportPage(c, 400, reportPageData{
+			Title:   "Invalid link",
+			Message: "This link is incomplete. Open it again from the email.",
+		})
+	}
+
+	return renderReportPage(c, 200, reportPageData{Token: token})
+}
+
+// Report signs the user out everywhere once they confirmed the login was
+// not theirs.
+func (h *LoginAlertHandler) Report(c *fiber.Ctx) error {
+	if err := h.alertService.ReportLogin(c.FormValue("token"), c.IP(), c.Get("User-Agent")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return renderReportPage(c, 400, reportPageData{
+				Title:   "Invalid link",
+				Message: "This link is incomplete. Open it again from the email.",
+			})
+		case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrNotFound):
+			return renderReportPage(c, 404, reportPageData{
+				Title:   "Link expired",
+				Message: "This link was already used or has expired.",
+			})
+		default:
+			return renderReportPage(c, 500, reportPageData{
+				Title:   "Something went wrong",
+				Message: "Your report could not be processed. Please try again.",
+			})
+		}
+	}
+
+	return renderReportPage(c, 200, reportPageData{
+		Title:   "All sessions signed out",
+		Message: "Your account has been signed out everywhere. Check your email for a link to reset your password.",
+	})
+}
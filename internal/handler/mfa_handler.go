@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MFAHandler struct {
+	mfaService *services.MFAService
+}
+
+func NewMFAHandler(ms *services.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: ms}
+}
+
+func mfaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return c.Status(400).JSON(fiber.Map{"error": "invalid input"})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return c.Status(401).JSON(fiber.Map{"error": "invalid code or password"})
+	case errors.Is(err, services.ErrOTPExpired):
+		return c.Status(401).JSON(fiber.Map{"error": "code expired; request a new one"})
+	case errors.Is(err, services.ErrOTPThrottled):
+		return c.Status(429).JSON(fiber.Map{"error": "a code was sent recently; try again later"})
+	case errors.Is(err, services.ErrPhoneNotVerified):
+		return c.Status(409).JSON(fiber.Map{"error": "verify your phone number first"})
+	case errors.Is(err, services.ErrAccountSuspended):
+		return c.Status(403).JSON(fiber.Map{"error": "account suspended"})
+	case errors.Is(err, services.ErrMustResetPassword):
+		return c.Status(403).JSON(fiber.Map{"error": "password reset required"})
+	case errors.Is(err, services.ErrPendingDeletion):
+		return c.Status(403).JSON(fiber.Map{"error": "account scheduled for deletion; cancel it to log in again"})
+	case errors.Is(err, services.ErrSessionLimit):
+		return c.Status(409).JSON(fiber.Map{"error": "too many active sessions; log out elsewhere first"})
+	case errors.Is(err, services.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
+
+/* ============================
+   Login second step (public)
+============================ */
+
+func (h *MFAHandler) VerifyLogin(c *fiber.Ctx) error {
+	var req struct {
+		MFAToken       string `json:"mfa_token"`
+		Code           string `json:"code"`
+		RememberDevice bool   `json:"remember_device"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	tokens, err := h.mfaService.VerifyChallenge(req.MFAToken, req.Code, req.RememberDevice, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, services.ErrOTPExpired) {
+			return c.Status(401).JSON(fiber.Map{"error": "login expired; log in again"})
+		}
+		return mfaError(c, err)
+	}
+
+	return loginResponse(c, tokens)
+}
+
+func (h *MFAHandler) ResendLogin(c *fiber.Ctx) error {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.mfaService.ResendChallenge(req.MFAToken, c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return c.Status(401).JSON(fiber.Map{"error": "login expired; log in again"})
+		}
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "code sent",
+	})
+}
+
+/* ============================
+   Phone verification and enrollment
+============================ */
+
+func (h *MFAHandler) StartPhoneVerification(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.mfaService.StartPhoneVerification(userID, c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return c.Status(400).JSON(fiber.Map{"error": "set a phone number first"})
+		}
+		return mfaError(c, err)
+	}
+
+	return c.Status(202).JSON(fiber.Map{
+		"message": "verification code sent",
+	})
+}
+
+func (h *MFAHandler) ConfirmPhone(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.mfaService.ConfirmPhone(userID, req.Code, c.IP(), c.Get("User-Agent")); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "phone number verified",
+	})
+}
+
+func (h *MFAHandler) EnableSMS(c *fiber.Ctx) error {
+	return h.toggleSMS(c, true)
+}
+
+func (h *MFAHandler) DisableSMS(c *fiber.Ctx) error {
+	return h.toggleSMS(c, false)
+}
+
+func (h *MFAHandler) toggleSMS(c *fiber.Ctx, enable bool) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	var err error
+	if enable {
+		err = h.mfaService.EnableSMS(userID, req.Password, c.IP(), c.Get("User-Agent"))
+	} else {
+		err = h.mfaService.DisableSMS(userID, req.Password, c.IP(), c.Get("User-Agent"))
+	}
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"mfa_sms_enabled": enable,
+	})
+}
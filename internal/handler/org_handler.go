@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrgHandler struct {
+	orgService *services.OrgService
+}
+
+func NewOrgHandler(osv *services.OrgService) *OrgHandler {
+	return &OrgHandler{orgService: osv}
+}
+
+func orgError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return c.Status(400).JSON(fiber.Map{"error": "invalid input"})
+	case errors.Is(err, services.ErrForbidden):
+		return c.Status(403).JSON(fiber.Map{"error": "forbidden"})
+	case errors.Is(err, services.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, services.ErrInvalidInvite):
+		return c.Status(410).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPasswordMissing):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrOrgExists), errors.Is(err, services.ErrLastOwner), errors.Is(err, services.ErrAlreadyMember):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
+
+func (h *OrgHandler) CreateOrg(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	org, err := h.orgService.CreateOrganization(userID, req.Name, req.Slug, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"organization": org,
+	})
+}
+
+func (h *OrgHandler) ListOrgs(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgs, err := h.orgService.ListOrganizations(userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "failed to fetch organizations",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"organizations": orgs,
+	})
+}
+
+func (h *OrgHandler) ListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+
+	members, err := h.orgService.ListMembers(uint(orgID), userID)
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"members": members,
+	})
+}
+
+func (h *OrgHandler) UpdateMemberRole(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+	memberID, err := c.ParamsInt("userID")
+	if err != nil || memberID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := h.orgService.UpdateMemberRole(
+		uint(orgID),
+		userID,
+		uint(memberID),
+		models.OrgRole(req.Role),
+		c.IP(),
+		c.Get("User-Agent"),
+	); err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "member role updated",
+	})
+}
+
+func (h *OrgHandler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+	memberID, err := c.ParamsInt("userID")
+	if err != nil || memberID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid userID"})
+	}
+
+	if err := h.orgService.RemoveMember(
+		uint(orgID),
+		userID,
+		uint(memberID),
+		c.IP(),
+		c.Get("User-Agent"),
+	); err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "member removed",
+	})
+}
+
+func (h *OrgHandler) CreateInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	inv, err := h.orgService.CreateInvitation(
+		uint(orgID),
+		userID,
+		req.Email,
+		models.OrgRole(req.Role),
+		c.IP(),
+		c.Get("User-Agent"),
+	)
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"invitation": inv,
+	})
+}
+
+func (h *OrgHandler) ListInvitations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+
+	invites, err := h.orgService.ListInvitations(uint(orgID), userID)
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"invitations": invites,
+	})
+}
+
+func (h *OrgHandler) RevokeInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	orgID, err := c.ParamsInt("orgID")
+	if err != nil || orgID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid orgID"})
+	}
+	inviteID, err := c.ParamsInt("inviteID")
+	if err != nil || inviteID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid inviteID"})
+	}
+
+	if err := h.orgService.RevokeInvitation(
+		uint(orgID),
+		userID,
+		uint(inviteID),
+		c.IP(),
+		c.Get("User-Agent"),
+	); err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "invitation revoked",
+	})
+}
+
+func (h *OrgHandler) AcceptInvitation(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "token required",
+		})
+	}
+
+	inv, err := h.orgService.AcceptInvitation(req.Token, req.Password, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":         "invitation accepted",
+		"organization_id": inv.OrganizationID,
+		"role":            inv.Role,
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProfileHandler struct {
+	profileService *services.ProfileService
+}
+
+func NewProfileHandler(ps *services.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profileService: ps}
+}
+
+func (h *ProfileHandler) GetMe(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	user, err := h.profileService.GetProfile(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+func (h *ProfileHandler) UpdateMe(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req services.ProfileUpdate
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	user, err := h.profileService.UpdateProfile(userID, req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "invalid profile: check username, phone (E.164), display_name, locale, timezone, avatar_url (https) and user_metadata (JSON object, max 16KB)"})
+		case errors.Is(err, services.ErrMFAEnabled):
+			return c.Status(409).JSON(fiber.Map{"error": "disable SMS two-factor before changing your phone number"})
+		case errors.Is(err, services.ErrUserExists):
+			return c.Status(409).JSON(fiber.Map{"error": "username or phone already in use"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const scimContentType = "application/scim+json"
+
+type SCIMHandler struct {
+	scimService *services.SCIMService
+}
+
+func NewSCIMHandler(scs *services.SCIMService) *SCIMHandler {
+	return &SCIMHandler{scimService: scs}
+}
+
+func scimJSON(c *fiber.Ctx, status int, body any) error {
+	return c.Status(status).JSON(body, scimContentType)
+}
+
+// scimError writes err in the SCIM error format.
+func scimError(c *fiber.Ctx, err error) error {
+	status, scimType, detail := 500, "", "internal server error"
+
+	var se *services.SCIMError
+	if errors.As(err, &se) {
+		status, scimType, detail = se.Status, se.ScimType, se.Detail
+	} else {
+		log.Printf("scim request failed: %v", err)
+	}
+
+	body := fiber.Map{
+		"schemas": []string{services.SCIMSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+
+	return scimJSON(c, status, body)
+}
+
+// scimBody decodes the request body; SCIM clients send application/scim+json
+// which BodyParser does not accept.
+func scimBody(c *fiber.Ctx, dst any) error {
+	if err := json.Unmarshal(c.Body(), dst); err != nil {
+		return &services.SCIMError{Status: 400, ScimType: "invalidSyntax", Detail: "invalid JSON body"}
+	}
+	return nil
+}
+
+func scimContext(c *fiber.Ctx) (uint, uint) {
+	return c.Locals("scim_org_id").(uint), c.Locals("scim_token_id").(uint)
+}
+
+func scimPaging(c *fiber.Ctx) (int, int) {
+	return c.QueryInt("startIndex", 1), c.QueryInt("count", -1)
+}
+
+func (h *SCIMHandler) ServiceProviderConfig(c *fiber.Ctx) error {
+	return scimJSON(c, 200, fiber.Map{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          fiber.Map{"supported": true},
+		"bulk":           fiber.Map{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         fiber.Map{"supported": true, "maxResults": services.SCIMMaxResults},
+		"changePassword": fiber.Map{"supported": false},
+		"sort":           fiber.Map{"supported": false},
+		"etag":           fiber.Map{"supported": false},
+		"authenticationSchemes": []fiber.Map{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Per-organization SCIM token",
+		}},
+	})
+}
+
+/* ============================
+   Users
+============================ */
+
+func (h *SCIMHandler) ListUsers(c *fiber.Ctx) error {
+	orgID, _ := scimContext(c)
+	startIndex, count := scimPaging(c)
+
+	list, err := h.scimService.ListUsers(orgID, c.Query("filter"), startIndex, count)
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, list)
+}
+
+func (h *SCIMHandler) GetUser(c *fiber.Ctx) error {
+	orgID, _ := scimContext(c)
+
+	user, err := h.scimService.GetUser(orgID, c.Params("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, user)
+}
+
+func (h *SCIMHandler) CreateUser(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	var in services.SCIMUserInput
+	if err := scimBody(c, &in); err != nil {
+		return scimError(c, err)
+	}
+
+	user, err := h.scimService.CreateUser(orgID, tokenID, in, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	c.Location(user.Meta.Location)
+	return scimJSON(c, 201, user)
+}
+
+func (h *SCIMHandler) ReplaceUser(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	var in services.SCIMUserInput
+	if err := scimBody(c, &in); err != nil {
+		return scimError(c, err)
+	}
+
+	user, err := h.scimService.ReplaceUser(orgID, tokenID, c.Params("id"), in, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, user)
+}
+
+func (h *SCIMHandler) PatchUser(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	var req services.SCIMPatchRequest
+	if err := scimBody(c, &req); err != nil {
+		return scimError(c, err)
+	}
+
+	user, err := h.scimService.PatchUser(orgID, tokenID, c.Params("id"), req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, user)
+}
+
+func (h *SCIMHandler) DeleteUser(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	if err := h.scimService.DeleteUser(orgID, tokenID, c.Params("id"), c.IP(), c.Get("User-Agent")); err != nil {
+		return scimError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+/* ============================
+   Groups
+============================ */
+
+func (h *SCIMHandler) ListGroups(c *fiber.Ctx) error {
+	orgID, _ := scimContext(c)
+	startIndex, count := scimPaging(c)
+	withMembers := !strings.Contains(strings.ToLower(c.Query("excludedAttributes")), "members")
+
+	list, err := h.scimService.ListGroups(orgID, c.Query("filter"), startIndex, count, withMembers)
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, list)
+}
+
+func (h *SCIMHandler) GetGroup(c *fiber.Ctx) error {
+	orgID, _ := scimContext(c)
+
+	group, err := h.scimService.GetGroup(orgID, c.Params("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, group)
+}
+
+func (h *SCIMHandler) ReplaceGroup(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	var in services.SCIMGroupInput
+	if err := scimBody(c, &in); err != nil {
+		return scimError(c, err)
+	}
+
+	group, err := h.scimService.ReplaceGroup(orgID, tokenID, c.Params("id"), in, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, group)
+}
+
+func (h *SCIMHandler) PatchGroup(c *fiber.Ctx) error {
+	orgID, tokenID := scimContext(c)
+
+	var req services.SCIMPatchRequest
+	if err := scimBody(c, &req); err != nil {
+		return scimError(c, err)
+	}
+
+	group, err := h.scimService.PatchGroup(orgID, tokenID, c.Params("id"), req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	return scimJSON(c, 200, group)
+}
+
+// UnsupportedGroupOp answers group creation and deletion: groups are the
+// fixed organization roles.
+func (h *SCIMHandler) UnsupportedGroupOp(c *fiber.Ctx) error {
+	return scimError(c, &services.SCIMError{Status: 501, Detail: "groups are organization roles and cannot be created or deleted"})
+}
+
+/* ============================
+   Token management (admin API)
+============================ */
+
+func (h *SCIMHandler) CreateToken(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	orgID := c.Locals("org_id").(uint)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "no active organization"})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	token, rowToken, err := h.scimService.CreateToken(adminID, orgID, req.Name, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+		case errors.Is(err, services.ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "organization not found"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	// the raw token is only ever returned here
+	return c.Status(201).JSON(fiber.Map{
+		"scim_token": token,
+		"token":      rowToken,
+	})
+}
+
+func (h *SCIMHandler) ListTokens(c *fiber.Ctx) error {
+	orgID := c.Locals("org_id").(uint)
+
+	tokens, err := h.scimService.ListTokens(orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"scim_tokens": tokens,
+	})
+}
+
+func (h *SCIMHandler) RevokeToken(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	orgID := c.Locals("org_id").(uint)
+
+	tokenID, err := c.ParamsInt("tokenID")
+	if err != nil || tokenID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid tokenID"})
+	}
+
+	if err := h.scimService.RevokeToken(adminID, orgID, uint(tokenID), c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "scim token not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "scim token revoked",
+	})
+}
@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ServiceAccountHandler struct {
+	serviceAccountService *services.ServiceAccountService
+}
+
+func NewServiceAccountHandler(sas *services.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{serviceAccountService: sas}
+}
+
+func serviceAccountError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return c.Status(400).JSON(fiber.Map{"error": "invalid input"})
+	case errors.Is(err, services.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "service account not found"})
+	case errors.Is(err, services.ErrForbidden):
+		return c.Status(409).JSON(fiber.Map{"error": "api key limit reached"})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
+
+// Token exchanges client credentials for a service access token.
+func (h *ServiceAccountHandler) Token(c *fiber.Ctx) error {
+	var req struct {
+		GrantType    string `json:"grant_type" form:"grant_type"`
+		ClientID     string `json:"client_id" form:"client_id"`
+		ClientSecret string `json:"client_secret" form:"client_secret"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.GrantType != "client_credentials" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "unsupported grant_type",
+		})
+	}
+
+	tokens, err := h.serviceAccountService.IssueToken(req.ClientID, req.ClientSecret, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			return c.Status(400).JSON(fiber.Map{"error": "client_id and client_secret required"})
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.Status(401).JSON(fiber.Map{"error": "invalid client credentials"})
+		default:
+			return c.Status(500).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token": tokens.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   tokens.ExpiresIn,
+	})
+}
+
+func (h *ServiceAccountHandler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Name           string   `json:"name"`
+		Description    string   `json:"description"`
+		Role           string   `json:"role"`
+		Scopes         []string `json:"scopes"`
+		OrganizationID uint     `json:"organization_id"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	sa, secret, err := h.serviceAccountService.Create(
+		userID,
+		req.Name,
+		req.Description,
+		models.UserRole(req.Role),
+		req.Scopes,
+		req.OrganizationID,
+		c.IP(),
+		c.Get("User-Agent"),
+	)
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	// the client secret is only ever returned here
+	return c.Status(201).JSON(fiber.Map{
+		"service_account": sa,
+		"client_secret":   secret,
+	})
+}
+
+func (h *ServiceAccountHandler) List(c *fiber.Ctx) error {
+	orgID := uint(c.QueryInt("organization_id"))
+
+	accounts, err := h.serviceAccountService.List(orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "failed to fetch service accounts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"service_accounts": accounts,
+	})
+}
+
+func (h *ServiceAccountHandler) Get(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	sa, err := h.serviceAccountService.Get(uint(id))
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"service_account": sa,
+	})
+}
+
+func (h *ServiceAccountHandler) RotateSecret(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	secret, err := h.serviceAccountService.RotateSecret(userID, uint(id), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"client_secret": secret,
+	})
+}
+
+func (h *ServiceAccountHandler) Disable(c *fiber.Ctx) error {
+	return h.setDisabled(c, true)
+}
+
+func (h *ServiceAccountHandler) Enable(c *fiber.Ctx) error {
+	return h.setDisabled(c, false)
+}
+
+func (h *ServiceAccountHandler) setDisabled(c *fiber.Ctx, disabled bool) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	if err := h.serviceAccountService.SetDisabled(userID, uint(id), disabled, c.IP(), c.Get("User-Agent")); err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "service account updated",
+	})
+}
+
+func (h *ServiceAccountHandler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	if err := h.serviceAccountService.Delete(userID, uint(id), c.IP(), c.Get("User-Agent")); err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "service account deleted",
+	})
+}
+
+func (h *ServiceAccountHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	key, rowKey, err := h.serviceAccountService.CreateAPIKey(
+		userID,
+		uint(id),
+		req.Name,
+		req.Scopes,
+		time.Duration(req.ExpiresInDays)*24*time.Hour,
+		c.IP(),
+		c.Get("User-Agent"),
+	)
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"api_key": key,
+		"key":     rowKey,
+	})
+}
+
+func (h *ServiceAccountHandler) ListAPIKeys(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+
+	keys, err := h.serviceAccountService.ListAPIKeys(uint(id))
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+func (h *ServiceAccountHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	id, err := c.ParamsInt("serviceAccountID")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid serviceAccountID"})
+	}
+	keyID, err := c.ParamsInt("keyID")
+	if err != nil || keyID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid keyID"})
+	}
+
+	if err := h.serviceAccountService.RevokeAPIKey(userID, uint(id), uint(keyID), c.IP(), c.Get("User-Agent")); err != nil {
+		return serviceAccountError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "api key revoked",
+	})
+}
@@ -0,0 +1,96 @@
+// Package iplist matches addresses against a list of IPs and CIDR ranges,
+// such as an IP reputation feed.
+package iplist
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// List is a set of networks. A nil *List is valid and matches nothing.
+type List struct {
+	networks []*net.IPNet
+}
+
+// Load reads one IP or CIDR per line; blank lines and text after # are
+// ignored. It returns nil for an empty path.
+func Load(path string) (*List, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &List{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		network, err := parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		l.networks = append(l.networks, network)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func parse(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", entry)
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.networks)
+}
+
+// Contains reports whether ip is on the list. Unparsable addresses are not.
+func (l *List) Contains(ip string) bool {
+	if l == nil {
+		return false
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range l.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package iplist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestContains(t *testing.T) {
+	path := writeList(t, `# reputation feed
+203.0.113.7
+198.51.100.0/24   # whole range
+10.0.0.0/8
+
+2001:db8::1
+2001:db8:abcd::/48
+  192.0.2.128/25
+`)
+
+	l, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if l.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", l.Len())
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "203.0.113.7", want: true},
+		{ip: "203.0.113.8", want: false},
+		{ip: "198.51.100.0", want: true},
+		{ip: "198.51.100.255", want: true},
+		{ip: "198.51.101.0", want: false},
+		{ip: "10.255.255.255", want: true},
+		{ip: "11.0.0.0", want: false},
+		{ip: "192.0.2.127", want: false},
+		{ip: "192.0.2.128", want: true},
+		{ip: "192.0.2.255", want: true},
+		{ip: "::ffff:203.0.113.7", want: true},
+		{ip: "::ffff:198.51.100.9", want: true},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8::2", want: false},
+		{ip: "2001:DB8:ABCD:1234::5", want: true},
+		{ip: "2001:db8:abce::1", want: false},
+		{ip: "", want: false},
+		{ip: "not-an-ip", want: false},
+		{ip: "203.0.113.7:443", want: false},
+		{ip: "203.0.113.0/24", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := l.Contains(tt.ip); got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsBadEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not an ip", content: "example.com\n", wantErr: ":1:"},
+		{name: "bad cidr", content: "10.0.0.0/8\n10.0.0.0/33\n", wantErr: ":2:"},
+		{name: "bad v6 cidr", content: "2001:db8::/129\n", wantErr: ":1:"},
+		{name: "partial ip", content: "# header\n\n10.0.0\n", wantErr: ":3:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Load(writeList(t, tt.content))
+			if err == nil {
+				t.Fatalf("Load = %d entries, want an error", l.Len())
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not name line %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNilList(t *testing.T) {
+	l, err := Load("")
+	if err != nil || l != nil {
+		t.Fatalf(`Load("") = %v, %v; want nil, nil`, l, err)
+	}
+	if l.Contains("203.0.113.7") {
+		t.Error("nil list contains an address")
+	}
+	if l.Len() != 0 {
+		t.Errorf("nil list Len() = %d, want 0", l.Len())
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
@@ -0,0 +1,42 @@
+package security
+
+import "github.com/gofiber/fiber/v2"
+
+// ImpersonationGuard rejects impersonation tokens whose impersonation was
+// stopped before the token expired.
+func ImpersonationGuard(isActive func(impersonationID string) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, _ := c.Locals("impersonation_id").(string)
+		if id != "" && !isActive(id) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "impersonation has ended",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// BlockImpersonation rejects requests made with an impersonation token, for
+// sensitive actions only the real user may perform.
+func BlockImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id, _ := c.Locals("impersonation_id").(string); id != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "not allowed while impersonating",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireImpersonation only lets impersonation tokens through.
+func RequireImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id, _ := c.Locals("impersonation_id").(string); id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "not impersonating",
+			})
+		}
+		return c.Next()
+	}
+}
@@ -9,12 +9,38 @@ import (
 )
 
 type AccessClaims struct {
-	UserID uint   `josn:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID           uint   `josn:"user_id"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	OrgID            uint   `json:"org_id,omitempty"`
+	OrgRole          string `json:"org_role,omitempty"`
+	PrincipalType    string `json:"principal_type,omitempty"`
+	ServiceAccountID uint   `json:"service_account_id,omitempty"`
+	// Scopes limit what a service account token may do, as for API keys.
+	Scopes []string `json:"scopes,omitempty"`
+	// Act names the admin behind an impersonation token (RFC 8693 "act").
+	Act *ActorClaim `json:"act,omitempty"`
+	// Impersonating lets clients show a banner while an admin acts as the user.
+	Impersonating   bool   `json:"impersonating,omitempty"`
+	ImpersonationID string `json:"impersonation_id,omitempty"`
+	// OIDC standard profile claims, only set when enabled in JWTConfig.
+	Name     string `json:"name,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	Zoneinfo string `json:"zoneinfo,omitempty"`
+	Picture  string `json:"picture,omitempty"`
+	// AuthTime and AMR are the OIDC auth_time (when the user last entered
+	// credentials) and amr (how, e.g. "pwd", "sms") claims. They survive
+	// refresh and are renewed by re-authenticating.
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type ActorClaim struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
 type RefreshClaims struct {
 	UserID    uint   `json:"user_id"`
 	SessionID string `json:"session_id"`
@@ -22,14 +48,19 @@ type RefreshClaims struct {
 }
 
 func GenerateAccessToken(userID uint, email, role, secret string, ttl time.Duration) (string, error) {
-	claims := AccessClaims{
+	return SignAccessToken(AccessClaims{
 		UserID: userID,
 		Email:  email,
 		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	}, secret, ttl)
+}
+
+// SignAccessToken signs the given claims, filling in the registered
+// expiry and issued-at times.
+func SignAccessToken(claims AccessClaims, secret string, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -71,7 +102,85 @@ func ParseRefreshToken(tokenStr, secret string) (*RefreshClaims, error) {
 	return claims, nil
 }
 
+// TrustedDeviceClaims identify a remembered device. The token is signed
+// with the refresh secret, so Type keeps it from passing as anything else.
+type TrustedDeviceClaims struct {
+	Type        string `json:"typ"`
+	UserID      uint   `json:"user_id"`
+	DeviceID    string `json:"device_id"`
+	Fingerprint string `json:"fingerprint"`
+	jwt.RegisteredClaims
+}
+
+const trustedDeviceTokenType = "trusted_device"
+
+func GenerateTrustedDeviceToken(userID uint, deviceID, fingerprint, secret string, ttl time.Duration) (string, error) {
+	claims := TrustedDeviceClaims{
+		Type:        trustedDeviceTokenType,
+		UserID:      userID,
+		DeviceID:    deviceID,
+		Fingerprint: fingerprint,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+func ParseTrustedDeviceToken(tokenStr, secret string) (*TrustedDeviceClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenStr,
+		&TrustedDeviceClaims{},
+		func(t *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+	)
+
+	if err != nil || !token.Valid {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TrustedDeviceClaims)
+	if !ok || claims.Type != trustedDeviceTokenType {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
+
+func ParseAccessToken(tokenStr, secret string) (*AccessClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenStr,
+		&AccessClaims{},
+		func(t *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+	)
+
+	if err != nil || !token.Valid {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*AccessClaims)
+	if !ok {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
 func JWT(secret string) fiber.Handler {
+	return Authenticate(secret, nil, nil)
+}
+
+// Authenticate accepts "Authorization: Bearer <jwt>" and, when verifyAPIKey
+// is set, "Authorization: ApiKey <key>". Both populate the same c.Locals.
+// Service account tokens are only accepted while activeServiceAccount
+// reports the account as enabled.
+func Authenticate(secret string, verifyAPIKey APIKeyVerifier, activeServiceAccount ServiceAccountChecker) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 
@@ -82,39 +191,59 @@ func JWT(secret string) fiber.Handler {
 		}
 
 		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "ApiKey" && verifyAPIKey != nil {
+			principal, err := verifyAPIKey(parts[1], c.IP())
+			if err != nil || principal == nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"message": "invalid or expaired api key",
+				})
+			}
+
+			setPrincipal(c, principal, AuthMethodAPIKey)
+			return c.Next()
+		}
+
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "token is not in valid formate",
 			})
 		}
 
-		tokenStr := parts[1]
-
-		token, err := jwt.ParseWithClaims(
-			tokenStr,
-			&AccessClaims{},
-			func(t *jwt.Token) (interface{}, error) {
-				return []byte(secret), nil
-			},
-		)
-
-		if err != nil || !token.Valid {
+		claims, err := ParseAccessToken(parts[1], secret)
+		if err != nil || claims == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "invalid or expaired token",
 			})
 		}
 
-		claims, ok := token.Claims.(*AccessClaims)
-		if !ok {
+		if claims.PrincipalType == PrincipalService && (activeServiceAccount == nil || !activeServiceAccount(claims.ServiceAccountID)) {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"messagae": "invalid token",
+				"message": "service account disabled or deleted",
 			})
 		}
 
-		c.Locals("user_id", claims.UserID)
-		c.Locals("email", claims.Email)
-		c.Locals("role", claims.Role)
+		setPrincipal(c, &Principal{
+			UserID:           claims.UserID,
+			Email:            claims.Email,
+			Role:             claims.Role,
+			OrgID:            claims.OrgID,
+			OrgRole:          claims.OrgRole,
+			PrincipalType:    claims.PrincipalType,
+			ServiceAccountID: claims.ServiceAccountID,
+			Scopes:           claims.Scopes,
+			ImpersonationID:  claims.ImpersonationID,
+			ImpersonatorID:   impersonatorID(claims),
+			AuthTime:         claims.AuthTime,
+			AMR:              claims.AMR,
+		}, AuthMethodJWT)
 
 		return c.Next()
 	}
 }
+
+func impersonatorID(claims *AccessClaims) uint {
+	if claims.Act == nil {
+		return 0
+	}
+	return claims.Act.UserID
+}
@@ -0,0 +1,24 @@
+package security
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRecentAuth lets a request through only if the user entered their
+// credentials within maxAge, per the access token's auth_time. Others get
+// 403 with "reauthenticate": true and should call /auth/reauthenticate.
+// API keys and service accounts never pass.
+func RequireRecentAuth(maxAge time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authTime, _ := c.Locals("auth_time").(int64)
+		if authTime == 0 || time.Since(time.Unix(authTime, 0)) > maxAge {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message":        "recent authentication required",
+				"reauthenticate": true,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package security
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const scimErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+// SCIMTokenVerifier resolves a SCIM bearer token to the organization it
+// provisions and the token's ID.
+type SCIMTokenVerifier func(rowToken string) (orgID, tokenID uint, err error)
+
+// SCIMAuth authenticates identity providers with per-organization SCIM
+// tokens. Failures use the SCIM error format since SCIM clients parse it.
+func SCIMAuth(verify SCIMTokenVerifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return scimUnauthorized(c)
+		}
+
+		orgID, tokenID, err := verify(token)
+		if err != nil {
+			return scimUnauthorized(c)
+		}
+
+		c.Locals("scim_org_id", orgID)
+		c.Locals("scim_token_id", tokenID)
+		return c.Next()
+	}
+}
+
+func scimUnauthorized(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="scim"`)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"schemas": []string{scimErrorSchema},
+		"status":  "401",
+		"detail":  "invalid or missing scim token",
+	}, "application/scim+json")
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type APIKey struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           *uint      `json:"user_id"`
+	ServiceAccountID *uint      `json:"service_account_id"`
+	OrganizationID   *uint      `json:"organization_id"`
+	Name             string     `json:"name" gorm:"not null"`
+	Prefix           string     `json:"prefix" gorm:"type:varchar(16);uniqueIndex;not null"`
+	KeyHash          string     `json:"-" gorm:"not null"`
+	Scopes           string     `json:"scopes" gorm:"not null;default:''"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       string     `json:"last_used_ip" gorm:"type:varchar(45)"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the space separated scopes as a slice.
+func (k APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k APIKey) Active() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
@@ -1,12 +1,24 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type AuditLog struct {
-	ID        uint      `gorm:"primeryKey"`
-	UserID    *uint     `gorm:"index"`
-	Event     string    `gorm:"type:varchar(50);index"`
-	IP        string    `gorm:"type:varchar(45)"`
-	UserAgent string    `gorm:"type:text"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-}	
+	ID               uint            `gorm:"primeryKey"`
+	UserID           *uint           `gorm:"index"`
+	PrincipalType    string          `gorm:"type:varchar(20);default:user"`
+	ServiceAccountID *uint           `gorm:"index"`
+	Event            string          `gorm:"type:varchar(50);index"`
+	IP               string          `gorm:"type:varchar(45)"`
+	UserAgent        string          `gorm:"type:text"`
+	Browser          string          `gorm:"type:varchar(50)"`
+	OS               string          `gorm:"column:os;type:varchar(50)"`
+	DeviceType       string          `gorm:"type:varchar(20)"`
+	Country          string          `gorm:"type:varchar(2)"`
+	City             string          `gorm:"type:text"`
+	ASN              uint            `gorm:"column:asn"`
+	Metadata         json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt        time.Time       `gorm:"autoCreateTime"`
+}
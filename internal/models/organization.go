@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+type OrgRole string
+
+const (
+	OrgOwner  OrgRole = "owner"
+	OrgAdmin  OrgRole = "admin"
+	OrgMember OrgRole = "member"
+)
+
+func (r OrgRole) Valid() bool {
+	switch r {
+	case OrgOwner, OrgAdmin, OrgMember:
+		return true
+	}
+	return false
+}
+
+type Organization struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+type Membership struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null"`
+	UserID         uint      `json:"user_id" gorm:"not null"`
+	Role           OrgRole   `json:"role" gorm:"type:text;not null;default:'member'"`
+	ExternalID     *string   `json:"external_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// SuspendedAt is set when the organization's SCIM client deactivates
+	// the user, who keeps the account but cannot act in the organization.
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+}
+
+func (Membership) TableName() string {
+	return "memberships"
+}
+
+// OrgMembership is a membership joined with its organization, used when
+// listing the organizations a user belongs to.
+type OrgMembership struct {
+	OrganizationID uint    `json:"organization_id"`
+	Name           string  `json:"name"`
+	Slug           string  `json:"slug"`
+	Role           OrgRole `json:"role"`
+}
+
+type OrgInvitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null"`
+	Email          string     `json:"email" gorm:"not null"`
+	Role           OrgRole    `json:"role" gorm:"type:text;not null;default:'member'"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedBy      *uint      `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (OrgInvitation) TableName() string {
+	return "org_invitations"
+}
+
+// Pending reports whether the invitation can still be accepted.
+func (i OrgInvitation) Pending() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type RolePermission struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Role      string    `json:"role" gorm:"not null"`
+	Action    string    `json:"action" gorm:"not null"`
+	Resource  string    `json:"resource" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SCIMToken authenticates an identity provider provisioning users into a
+// single organization over SCIM.
+type SCIMToken struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null"`
+	Name           string     `json:"name" gorm:"not null"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedBy      *uint      `json:"created_by"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (SCIMToken) TableName() string {
+	return "scim_tokens"
+}
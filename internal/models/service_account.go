@@ -0,0 +1,37 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+// ServiceAccount is a non-human principal. It never has a password and
+// authenticates with a client secret or API keys instead.
+type ServiceAccount struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	Name             string     `json:"name" gorm:"not null"`
+	Description      string     `json:"description" gorm:"not null;default:''"`
+	Role             UserRole   `json:"role" gorm:"type:text;not null;default:'user'"`
+	Scopes           string     `json:"scopes" gorm:"not null;default:''"`
+	OrganizationID   *uint      `json:"organization_id"`
+	OwnerUserID      *uint      `json:"owner_user_id"`
+	ClientID         string     `json:"client_id" gorm:"type:varchar(32);uniqueIndex;not null"`
+	ClientSecretHash string     `json:"-"`
+	DisabledAt       *time.Time `json:"disabled_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// ScopeList returns the space separated scopes as a slice.
+func (sa ServiceAccount) ScopeList() []string {
+	return strings.Fields(sa.Scopes)
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type UserRole string
 
@@ -9,13 +12,46 @@ const (
 	User  UserRole = "user"
 )
 
+type UserStatus string
+
+const (
+	StatusActive    UserStatus = "active"
+	StatusSuspended UserStatus = "suspended"
+	// StatusPendingDeletion accounts are deleted once DeletionScheduledAt
+	// passes unless the user cancels first.
+	StatusPendingDeletion UserStatus = "pending_deletion"
+	// StatusDeleted marks anonymized accounts kept instead of hard deleted.
+	StatusDeleted UserStatus = "deleted"
+)
+
 type UserModel struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
-	Role      UserRole  `json:"role" gorm:"type:text;not null;default:'user'"`
-	Password  string    `json:"-" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Email string `json:"email" gorm:"uniqueIndex;not null"`
+	// Username (lowercase) and Phone (E.164) are optional alternate login
+	// identifiers; NULL when unset so the unique indexes ignore them.
+	Username *string `json:"username,omitempty"`
+	Phone    *string `json:"phone,omitempty"`
+	// PhoneVerifiedAt is cleared whenever the phone number changes.
+	PhoneVerifiedAt     *time.Time `json:"phone_verified_at,omitempty"`
+	MFASMSEnabled       bool       `json:"mfa_sms_enabled" gorm:"column:mfa_sms_enabled;not null;default:false"`
+	Role                UserRole   `json:"role" gorm:"type:text;not null;default:'user'"`
+	Status              UserStatus `json:"status" gorm:"type:text;not null;default:'active'"`
+	MustResetPassword   bool       `json:"must_reset_password" gorm:"not null;default:false"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DisplayName         string     `json:"display_name" gorm:"not null;default:''"`
+	Locale              string     `json:"locale" gorm:"not null;default:''"`
+	Timezone            string     `json:"timezone" gorm:"not null;default:''"`
+	AvatarURL           string     `json:"avatar_url" gorm:"not null;default:''"`
+	// AppMetadata is managed by admins and other services; users can read
+	// but not change it. UserMetadata is editable by the user.
+	AppMetadata  json.RawMessage `json:"app_metadata" gorm:"type:jsonb;not null;default:'{}'"`
+	UserMetadata json.RawMessage `json:"user_metadata" gorm:"type:jsonb;not null;default:'{}'"`
+	Password     string          `json:"-" gorm:"not null"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	// ManagedByOrgID is the organization whose SCIM client created the
+	// account; only it may change the account over SCIM.
+	ManagedByOrgID *uint `json:"managed_by_org_id,omitempty"`
 }
 
 func (UserModel) TableName() string {
@@ -7,11 +7,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/geoip"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
 	"github.com/redis/go-redis/v9"
 )
 
 type SessionRepository struct {
 	rdb *redis.Client
+	geo *geoip.Resolver
 }
 
 type SessionInfo struct {
@@ -19,41 +22,67 @@ type SessionInfo struct {
 	IP        string `json:"ip"`
 	Browser   string `json:"browser"`
 	OS        string `json:"os"`
-	CreatedAt int64  `json:"created_at"`
+	// parsed from the User-Agent by the useragent package
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceType     string `json:"device_type"`
+	CreatedAt      int64  `json:"created_at"`
+	// StartedAt and IP are from the original login; they survive refresh
+	// rotation while CreatedAt is reset by it. LastIP and LastActiveAt are
+	// from the latest refresh.
+	StartedAt     int64  `json:"started_at"`
+	LastActiveAt  int64  `json:"last_active_at"`
+	LastIP        string `json:"last_ip"`
+	IdleExpiresAt int64  `json:"idle_expires_at,omitempty"`
+	ExpiresAt     int64  `json:"expires_at,omitempty"`
+	// Location is looked up from LastIP.
+	Location geoip.Location `json:"location"`
+	// AuthTime and AMR say when and how the user last authenticated in
+	// this session.
+	AuthTime int64          `json:"auth_time"`
+	AMR      []string       `json:"amr,omitempty"`
+	Binding  SessionBinding `json:"-"`
+	// Name is set by the user to tell their devices apart.
+	Name      string `json:"name,omitempty"`
+	IsCurrent bool   `json:"is_current"`
 }
 
-func NewSessionRepository(rdb *redis.Client) *SessionRepository {
-	return &SessionRepository{rdb: rdb}
+// SessionLifetime carries a session across refresh rotation: its limits
+// (zero values mean no limit), where it started, when and how the user last
+// authenticated and the user's name for it.
+type SessionLifetime struct {
+	StartedAt   time.Time
+	ExpiresAt   time.Time
+	IdleTimeout time.Duration
+	LoginIP     string
+	Name        string
+	// AuthTime and AMR become the auth_time and amr access token claims.
+	// A zero AuthTime means StartedAt.
+	AuthTime time.Time
+	AMR      []string
+	// Binding is the client the session is bound to.
+	Binding SessionBinding
 }
 
-func parseDevice(ua string) (browser, os string) {
-	switch {
-	case strings.Contains(ua, "Chrome"):
-		browser = "Chrome"
-	case strings.Contains(ua, "Firefox"):
-		browser = "Firefox"
-	case strings.Contains(ua, "Safari"):
-		browser = "Safari"
-	default:
-		browser = "Unknown"
-	}
+// SessionBinding is a coarse fingerprint of the client holding a session,
+// compared on refresh to spot a refresh token used elsewhere. Empty fields
+// mean the session predates binding.
+type SessionBinding struct {
+	UAFamily string
+	IPSubnet string
+}
 
-	switch {
-	case strings.Contains(ua, "Windows"):
-		os = "Windows"
-	case strings.Contains(ua, "Mac"):
-		os = "macOS"
-	case strings.Contains(ua, "Linux"):
-		os = "Linux"
-	case strings.Contains(ua, "Android"):
-		os = "Android"
-	case strings.Contains(ua, "iPhone"):
-		os = "iOS"
-	default:
-		os = "Unknown"
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
 	}
+	return t.Unix()
+}
 
-	return
+// NewSessionRepository takes an optional GeoIP resolver (nil skips the
+// lookup).
+func NewSessionRepository(rdb *redis.Client, geo *geoip.Resolver) *SessionRepository {
+	return &SessionRepository{rdb: rdb, geo: geo}
 }
 
 /* ============================
@@ -67,10 +96,24 @@ func (r *SessionRepository) Create(
 	ip string,
 	userAgent string,
 	ttl time.Duration,
+	lifetime SessionLifetime,
 ) error {
 
 	now := time.Now().Unix()
-	browser, os := parseDevice(userAgent)
+	startedAt := unixOrZero(lifetime.StartedAt)
+	if startedAt == 0 {
+		startedAt = now
+	}
+	authTime := unixOrZero(lifetime.AuthTime)
+	if authTime == 0 {
+		authTime = startedAt
+	}
+	loginIP := lifetime.LoginIP
+	if loginIP == "" {
+		loginIP = ip
+	}
+	device := useragent.Parse(userAgent)
+	loc := r.geo.Lookup(ip)
 	sessionKey := fmt.Sprintf("session:%s", sessionID)
 	userSessionsKey := fmt.Sprintf("user_session:%d", userID)
 
@@ -79,18 +122,40 @@ func (r *SessionRepository) Create(
 	pipe.HSet(
 		ctx,
 		sessionKey,
-		"ip", ip,
+		"ip", loginIP,
+		"last_ip", ip,
 		"user_agent", userAgent,
-		"os", os,
-		"brwoser", browser,
+		"os", device.OS,
+		"os_version", device.OSVersion,
+		"browser", device.Browser,
+		"browser_version", device.BrowserVersion,
+		"device_type", device.Device,
+		"country", loc.Country,
+		"country_name", loc.CountryName,
+		"city", loc.City,
+		"asn", loc.ASN,
+		"as_org", loc.ASOrg,
 		"user_id", userID,
 		"created_at", now,
+		"started_at", startedAt,
+		"last_active_at", now,
+		"expires_at", unixOrZero(lifetime.ExpiresAt),
+		"idle_timeout", int64(lifetime.IdleTimeout.Seconds()),
+		"name", lifetime.Name,
+		"auth_time", authTime,
+		"amr", strings.Join(lifetime.AMR, ","),
+		"binding_ua", lifetime.Binding.UAFamily,
+		"binding_subnet", lifetime.Binding.IPSubnet,
 	)
 
 	pipe.Expire(ctx, sessionKey, ttl)
 
+	// the index must outlive every session in it, and a session close to
+	// its absolute expiry gets a short ttl, so the index's TTL only ever
+	// grows: NX sets it on a new set, GT extends it
 	pipe.SAdd(ctx, userSessionsKey, sessionID)
-	pipe.Expire(ctx, userSessionsKey, ttl)
+	pipe.ExpireNX(ctx, userSessionsKey, ttl)
+	pipe.ExpireGT(ctx, userSessionsKey, ttl)
 
 	_, err := pipe.Exec(ctx)
 	return err
@@ -115,6 +180,172 @@ func (r *SessionRepository) GetUserID(
 	return uint(userID), nil
 }
 
+/* ============================
+   Get session
+============================ */
+
+// Get returns the session, or nil if it does not exist or has expired.
+func (r *SessionRepository) Get(
+	ctx context.Context,
+	sessionID string,
+) (*SessionInfo, error) {
+	data, err := r.rdb.HGetAll(ctx, "session:"+sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return sessionInfo(sessionID, data), nil
+}
+
+// Lifetime returns the limits to carry over to a rotated session.
+func (info *SessionInfo) Lifetime(idleTimeout time.Duration) SessionLifetime {
+	lifetime := SessionLifetime{
+		StartedAt:   time.Unix(info.StartedAt, 0),
+		IdleTimeout: idleTimeout,
+		LoginIP:     info.IP,
+		Name:        info.Name,
+		AuthTime:    time.Unix(info.AuthTime, 0),
+		AMR:         info.AMR,
+		Binding:     info.Binding,
+	}
+	if info.ExpiresAt != 0 {
+		lifetime.ExpiresAt = time.Unix(info.ExpiresAt, 0)
+	}
+	return lifetime
+}
+
+func sessionInfo(sessionID string, data map[string]string) *SessionInfo {
+	createdAt, _ := strconv.ParseInt(data["created_at"], 10, 64)
+	startedAt, _ := strconv.ParseInt(data["started_at"], 10, 64)
+	lastActiveAt, _ := strconv.ParseInt(data["last_active_at"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(data["expires_at"], 10, 64)
+	idleTimeout, _ := strconv.ParseInt(data["idle_timeout"], 10, 64)
+	asn, _ := strconv.ParseUint(data["asn"], 10, 64)
+	authTime, _ := strconv.ParseInt(data["auth_time"], 10, 64)
+
+	// sessions created before these fields existed
+	if startedAt == 0 {
+		startedAt = createdAt
+	}
+	if lastActiveAt == 0 {
+		lastActiveAt = createdAt
+	}
+	if authTime == 0 {
+		authTime = startedAt
+	}
+	lastIP := data["last_ip"]
+	if lastIP == "" {
+		lastIP = data["ip"]
+	}
+	browser := data["browser"]
+	if browser == "" {
+		browser = data["brwoser"]
+	}
+	deviceType := data["device_type"]
+	if deviceType == "" {
+		deviceType = useragent.DeviceUnknown
+	}
+
+	info := &SessionInfo{
+		SessionID:      sessionID,
+		IP:             data["ip"],
+		Browser:        browser,
+		BrowserVersion: data["browser_version"],
+		OS:             data["os"],
+		OSVersion:      data["os_version"],
+		DeviceType:     deviceType,
+		CreatedAt:      createdAt,
+		StartedAt:      startedAt,
+		LastActiveAt:   lastActiveAt,
+		LastIP:         lastIP,
+		ExpiresAt:      expiresAt,
+		Name:           data["name"],
+		AuthTime:       authTime,
+		Binding: SessionBinding{
+			UAFamily: data["binding_ua"],
+			IPSubnet: data["binding_subnet"],
+		},
+		Location: geoip.Location{
+			Country:     data["country"],
+			CountryName: data["country_name"],
+			City:        data["city"],
+			ASN:         uint(asn),
+			ASOrg:       data["as_org"],
+		},
+	}
+	if idleTimeout > 0 {
+		info.IdleExpiresAt = lastActiveAt + idleTimeout
+	}
+	if data["amr"] != "" {
+		info.AMR = strings.Split(data["amr"], ",")
+	}
+	return info
+}
+
+/* ============================
+   Authentication time
+============================ */
+
+// SetAuth records a re-authentication in the session, so refreshed access
+// tokens keep the new auth_time.
+func (r *SessionRepository) SetAuth(
+	ctx context.Context,
+	sessionID string,
+	authTime time.Time,
+	amr []string,
+) error {
+	sessionKey := fmt.Sprintf("session:%s", sessionID)
+	return r.rdb.HSet(ctx, sessionKey,
+		"auth_time", authTime.Unix(),
+		"amr", strings.Join(amr, ","),
+	).Err()
+}
+
+/* ============================
+   Device name
+============================ */
+
+func (r *SessionRepository) SetName(
+	ctx context.Context,
+	sessionID string,
+	name string,
+) error {
+	sessionKey := fmt.Sprintf("session:%s", sessionID)
+	return r.rdb.HSet(ctx, sessionKey, "name", name).Err()
+}
+
+/* ============================
+   Active organization
+============================ */
+
+func (r *SessionRepository) SetActiveOrg(
+	ctx context.Context,
+	sessionID string,
+	orgID uint,
+) error {
+	sessionKey := fmt.Sprintf("session:%s", sessionID)
+	return r.rdb.HSet(ctx, sessionKey, "org_id", orgID).Err()
+}
+
+func (r *SessionRepository) GetActiveOrg(
+	ctx context.Context,
+	sessionID string,
+) (uint, error) {
+	sessionKey := fmt.Sprintf("session:%s", sessionID)
+
+	orgID, err := r.rdb.HGet(ctx, sessionKey, "org_id").Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(orgID), nil
+}
+
 /* ============================
    Delete session
 ============================ */
@@ -170,7 +401,7 @@ func (r *SessionRepository) DeleteAll(
 		if expectedSessionID != "" && sid == expectedSessionID {
 			continue
 		}
-		pipe.Del(ctx ,"session:"+sid)
+		pipe.Del(ctx, "session:"+sid)
 	}
 
 	if expectedSessionID == "" {
@@ -184,6 +415,35 @@ func (r *SessionRepository) DeleteAll(
 	return err
 }
 
+// DeleteByOrg deletes the user's sessions whose active organization is
+// orgID, leaving sessions in other organizations alone.
+func (r *SessionRepository) DeleteByOrg(
+	ctx context.Context,
+	userID uint,
+	orgID uint,
+) error {
+	userKey := fmt.Sprintf("user_session:%d", userID)
+	sessionIDs, err := r.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, sid := range sessionIDs {
+		activeOrgID, err := r.GetActiveOrg(ctx, sid)
+		if err != nil {
+			return err
+		}
+		if activeOrgID != orgID {
+			continue
+		}
+		if err := r.Delete(ctx, sid, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 /* ============================
    List user sessions
 ============================ */
@@ -201,22 +461,24 @@ func (r *SessionRepository) ListByUsers(
 	}
 
 	sessions := make([]SessionInfo, 0)
+	stale := make([]any, 0)
 
 	for _, sid := range sessionIDs {
 		data, err := r.rdb.HGetAll(ctx, "session:"+sid).Result()
-		if err != nil || len(data) == 0 {
+		if err != nil {
+			continue
+		}
+		if len(data) == 0 {
+			stale = append(stale, sid)
 			continue
 		}
 
-		createdAt, _ := strconv.ParseInt(data["created_at"], 10, 64)
+		sessions = append(sessions, *sessionInfo(sid, data))
+	}
 
-		sessions = append(sessions, SessionInfo{
-			SessionID: sid,
-			IP:        data["ip"],
-			Browser:   data["browser"],
-			OS:        data["os"],
-			CreatedAt: createdAt,
-		})
+	// expired sessions leave their IDs behind in the set
+	if len(stale) > 0 {
+		r.rdb.SRem(ctx, userSessionsKey, stale...)
 	}
 
 	return sessions, nil
@@ -229,3 +491,8 @@ func (r *SessionRepository) ListByUsers(
 func (r *SessionRepository) Redis() *redis.Client {
 	return r.rdb
 }
+
+// GeoIP returns the resolver sessions are located with; it may be nil.
+func (r *SessionRepository) GeoIP() *geoip.Resolver {
+	return r.geo
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create stores the key with only the hash of rowKey.
+func (r *APIKeyRepository) Create(key *models.APIKey, rowKey string) error {
+	key.KeyHash = hashToken(rowKey)
+	return r.db.Create(key).Error
+}
+
+func (r *APIKeyRepository) ListByUser(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) ListByServiceAccount(serviceAccountID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.
+		Where("service_account_id = ?", serviceAccountID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) FindByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// MatchesHash reports whether rowKey hashes to the stored key hash.
+func (r *APIKeyRepository) MatchesHash(key *models.APIKey, rowKey string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashToken(rowKey)), []byte(key.KeyHash)) == 1
+}
+
+func (r *APIKeyRepository) Revoke(userID, keyID uint) (bool, error) {
+	res := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *APIKeyRepository) RevokeForServiceAccount(serviceAccountID, keyID uint) (bool, error) {
+	res := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND service_account_id = ? AND revoked_at IS NULL", keyID, serviceAccountID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *APIKeyRepository) TouchLastUsed(keyID uint, ip string) {
+	go r.db.Model(&models.APIKey{}).
+		Where("id = ?", keyID).
+		Updates(map[string]any{
+			"last_used_at": time.Now(),
+			"last_used_ip": ip,
+		})
+}
@@ -1,25 +1,110 @@
 package repositories
 
 import (
+	"encoding/json"
+	"sync"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/geoip"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
 	"gorm.io/gorm"
 )
 
 type AuditRepo struct {
-	db *gorm.DB
+	db  *gorm.DB
+	geo *geoip.Resolver
+	// pending counts writes still in flight, see Wait.
+	pending sync.WaitGroup
+}
+
+// NewAuditRepo takes an optional GeoIP resolver (nil skips the lookup).
+func NewAuditRepo(db *gorm.DB, geo *geoip.Resolver) *AuditRepo {
+	return &AuditRepo{db: db, geo: geo}
 }
 
-func NewAuditRepo(db *gorm.DB) *AuditRepo {
-	return &AuditRepo{db: db}
+// enrich fills in the parsed User-Agent and the IP's location so audit
+// queries can filter by device and country.
+func (r *AuditRepo) enrich(log *models.AuditLog) {
+	device := useragent.Parse(log.UserAgent)
+	log.Browser = device.Browser
+	log.OS = device.OS
+	log.DeviceType = device.Device
+
+	loc := r.geo.Lookup(log.IP)
+	log.Country = loc.Country
+	log.City = loc.City
+	log.ASN = loc.ASN
 }
 
 func (r *AuditRepo) Log(event string, userID *uint, ip, ua string) {
+	r.LogWithMetadata(event, userID, ip, ua, nil)
+}
+
+// LogWithMetadata records an audit event together with structured details
+// (e.g. the action and resource of a denied authorization check).
+func (r *AuditRepo) LogWithMetadata(event string, userID *uint, ip, ua string, metadata map[string]any) {
+	log := models.AuditLog{
+		UserID:        userID,
+		PrincipalType: models.PrincipalUser,
+		Event:         event,
+		IP:            ip,
+		UserAgent:     ua,
+	}
+
+	if len(metadata) > 0 {
+		if raw, err := json.Marshal(metadata); err == nil {
+			log.Metadata = raw
+		}
+	}
+	r.enrich(&log)
+
+	r.write(&log)
+}
+
+// LogServiceAccount records an event performed by a service account, marked
+// with principal type "service" so it is never mistaken for a user action.
+func (r *AuditRepo) LogServiceAccount(event string, serviceAccountID uint, ip, ua string, metadata map[string]any) {
 	log := models.AuditLog{
-		UserID:    userID,
-		Event:     event,
-		IP:        ip,
-		UserAgent: ua,
+		PrincipalType:    models.PrincipalService,
+		ServiceAccountID: &serviceAccountID,
+		Event:            event,
+		IP:               ip,
+		UserAgent:        ua,
+	}
+
+	if len(metadata) > 0 {
+		if raw, err := json.Marshal(metadata); err == nil {
+			log.Metadata = raw
+		}
 	}
+	r.enrich(&log)
+
+	r.write(&log)
+}
 
-	go r.db.Create(&log)
+// write stores the entry in the background so requests never wait on the
+// audit table.
+func (r *AuditRepo) write(log *models.AuditLog) {
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.db.Create(log)
+	}()
+}
+
+// Wait blocks until every audit entry logged so far is written. Short-lived
+// processes such as command-line tools call it before exiting.
+func (r *AuditRepo) Wait() {
+	r.pending.Wait()
+}
+
+// ListByUser returns every audit entry recorded against the user, oldest
+// first.
+func (r *AuditRepo) ListByUser(userID uint) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
 }
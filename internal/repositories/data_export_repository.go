@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+type DataExport struct {
+	ID        string `json:"export_id"`
+	UserID    uint   `json:"-"`
+	Status    string `json:"status"`
+	CreatedAt int64  `json:"created_at"`
+	ReadyAt   int64  `json:"ready_at,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// DataExportRepository keeps generated data exports in Redis so they expire
+// on their own. Archives are fetched with a download token stored hashed.
+type DataExportRepository struct {
+	rdb *redis.Client
+}
+
+func NewDataExportRepository(rdb *redis.Client) *DataExportRepository {
+	return &DataExportRepository{rdb: rdb}
+}
+
+func dataExportKey(exportID string) string {
+	return fmt.Sprintf("data_export:%s", exportID)
+}
+
+func dataExportArchiveKey(exportID string) string {
+	return fmt.Sprintf("data_export:%s:archive", exportID)
+}
+
+/* ============================
+   Export lifecycle
+============================ */
+
+// ClaimUserSlot allows one export per user per window. It reports false if
+// the user already requested one recently.
+func (r *DataExportRepository) ClaimUserSlot(ctx context.Context, userID uint, window time.Duration) (bool, error) {
+	key := fmt.Sprintf("data_export_user:%d", userID)
+	return r.rdb.SetNX(ctx, key, 1, window).Result()
+}
+
+func (r *DataExportRepository) Create(ctx context.Context, exportID string, userID uint, ttl time.Duration) error {
+	key := dataExportKey(exportID)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(
+		ctx,
+		key,
+		"user_id", userID,
+		"status", DataExportPending,
+		"created_at", time.Now().Unix(),
+	)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Get returns the export, or nil if it does not exist or has expired.
+func (r *DataExportRepository) Get(ctx context.Context, exportID string) (*DataExport, error) {
+	data, err := r.rdb.HGetAll(ctx, dataExportKey(exportID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	userID, _ := strconv.ParseUint(data["user_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(data["created_at"], 10, 64)
+	readyAt, _ := strconv.ParseInt(data["ready_at"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(data["expires_at"], 10, 64)
+
+	return &DataExport{
+		ID:        exportID,
+		UserID:    uint(userID),
+		Status:    data["status"],
+		CreatedAt: createdAt,
+		ReadyAt:   readyAt,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// MarkReady stores the archive and the hashed download token; all three
+// expire together after ttl.
+func (r *DataExportRepository) MarkReady(ctx context.Context, exportID string, archive []byte, rowToken string, ttl time.Duration) error {
+	now := time.Now()
+	key := dataExportKey(exportID)
+	tokenKey := fmt.Sprintf("data_export_token:%s", hashToken(rowToken))
+
+	pipe := r.rdb.TxPipeline()
+	pipe.Set(ctx, dataExportArchiveKey(exportID), archive, ttl)
+	pipe.Set(ctx, tokenKey, exportID, ttl)
+	pipe.HSet(
+		ctx,
+		key,
+		"status", DataExportReady,
+		"ready_at", now.Unix(),
+		"expires_at", now.Add(ttl).Unix(),
+	)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *DataExportRepository) MarkFailed(ctx context.Context, exportID string) error {
+	return r.rdb.HSet(ctx, dataExportKey(exportID), "status", DataExportFailed).Err()
+}
+
+/* ============================
+   Download
+============================ */
+
+// GetArchiveByToken returns the export and archive for a download token, or
+// nil if the token is unknown or expired.
+func (r *DataExportRepository) GetArchiveByToken(ctx context.Context, rowToken string) (*DataExport, []byte, error) {
+	exportID, err := r.rdb.Get(ctx, fmt.Sprintf("data_export_token:%s", hashToken(rowToken))).Result()
+	if err == redis.Nil {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export, err := r.Get(ctx, exportID)
+	if err != nil || export == nil {
+		return nil, nil, err
+	}
+
+	archive, err := r.rdb.Get(ctx, dataExportArchiveKey(exportID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return export, archive, nil
+}
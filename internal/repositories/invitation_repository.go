@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvitationUsed = errors.New("invitation already used, revoked or expired")
+
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create stores the invitation with only the hash of rowToken.
+func (r *InvitationRepository) Create(inv *models.OrgInvitation, rowToken string) error {
+	inv.TokenHash = hashToken(rowToken)
+	return r.db.Create(inv).Error
+}
+
+func (r *InvitationRepository) ListByOrg(orgID uint) ([]models.OrgInvitation, error) {
+	var invites []models.OrgInvitation
+	err := r.db.
+		Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Find(&invites).Error
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *InvitationRepository) FindByID(orgID, inviteID uint) (*models.OrgInvitation, error) {
+	var inv models.OrgInvitation
+
+	err := r.db.Where("id = ? AND organization_id = ?", inviteID, orgID).First(&inv).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+func (r *InvitationRepository) FindByToken(rowToken string) (*models.OrgInvitation, error) {
+	var inv models.OrgInvitation
+
+	err := r.db.Where("token_hash = ?", hashToken(rowToken)).First(&inv).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+func (r *InvitationRepository) Revoke(inviteID uint) error {
+	return r.db.Model(&models.OrgInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", inviteID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Accept consumes the invitation and adds the user to the organization in
+// one transaction. The conditional update makes the token single-use.
+func (r *InvitationRepository) Accept(inv *models.OrgInvitation, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.OrgInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", inv.ID, time.Now()).
+			Update("accepted_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return ErrInvitationUsed
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Membership{
+			OrganizationID: inv.OrganizationID,
+			UserID:         userID,
+			Role:           inv.Role,
+		}).Error
+	})
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginAlertRepository remembers the devices and countries each user has
+// logged in from, where their last login came from, and the "this wasn't
+// me" tokens sent in login alerts.
+type LoginAlertRepository struct {
+	rdb *redis.Client
+}
+
+func NewLoginAlertRepository(rdb *redis.Client) *LoginAlertRepository {
+	return &LoginAlertRepository{rdb: rdb}
+}
+
+// LoginHistory says what was new about a login.
+type LoginHistory struct {
+	// FirstLogin is set when nothing was known about the user yet, e.g. on
+	// their first login or after the history expired.
+	FirstLogin bool
+	NewDevice  bool
+	NewCountry bool
+}
+
+/* ============================
+   Known devices and countries
+============================ */
+
+// Record adds the device fingerprint and country (if known) to the user's
+// history and reports which of them were not there before. The history
+// expires after ttl without logins.
+func (r *LoginAlertRepository) Record(ctx context.Context, userID uint, fingerprint, country string, ttl time.Duration) (LoginHistory, error) {
+	devicesKey := fmt.Sprintf("known_devices:%d", userID)
+	countriesKey := fmt.Sprintf("known_countries:%d", userID)
+
+	pipe := r.rdb.TxPipeline()
+	existing := pipe.Exists(ctx, devicesKey)
+	addedDevice := pipe.SAdd(ctx, devicesKey, fingerprint)
+	var addedCountry *redis.IntCmd
+	if country != "" {
+		addedCountry = pipe.SAdd(ctx, countriesKey, country)
+		pipe.Expire(ctx, countriesKey, ttl)
+	}
+	pipe.Expire(ctx, devicesKey, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return LoginHistory{}, err
+	}
+
+	history := LoginHistory{
+		FirstLogin: existing.Val() == 0,
+		NewDevice:  addedDevice.Val() == 1,
+	}
+	if addedCountry != nil {
+		history.NewCountry = addedCountry.Val() == 1
+	}
+	return history, nil
+}
+
+// Known reports what Record would, without recording anything.
+func (r *LoginAlertRepository) Known(ctx context.Context, userID uint, fingerprint, country string) (LoginHistory, error) {
+	devicesKey := fmt.Sprintf("known_devices:%d", userID)
+	countriesKey := fmt.Sprintf("known_countries:%d", userID)
+
+	pipe := r.rdb.Pipeline()
+	existing := pipe.Exists(ctx, devicesKey)
+	knownDevice := pipe.SIsMember(ctx, devicesKey, fingerprint)
+	var knownCountry *redis.BoolCmd
+	if country != "" {
+		knownCountry = pipe.SIsMember(ctx, countriesKey, country)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return LoginHistory{}, err
+	}
+
+	history := LoginHistory{
+		FirstLogin: existing.Val() == 0,
+		NewDevice:  !knownDevice.Val(),
+	}
+	if knownCountry != nil {
+		history.NewCountry = !knownCountry.Val()
+	}
+	return history, nil
+}
+
+/* ============================
+   Last login
+============================ */
+
+// LastLogin is where and when a user last logged in from a located
+// address.
+type LastLogin struct {
+	At        time.Time
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+// GetLastLogin returns nil if no located login is known.
+func (r *LoginAlertRepository) GetLastLogin(ctx context.Context, userID uint) (*LastLogin, error) {
+	data, err := r.rdb.HGetAll(ctx, fmt.Sprintf("last_login:%d", userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var last LastLogin
+	var at int64
+	fmt.Sscan(data["at"], &at)
+	fmt.Sscan(data["lat"], &last.Latitude)
+	fmt.Sscan(data["lon"], &last.Longitude)
+	last.At = time.Unix(at, 0)
+	last.Country = data["country"]
+	return &last, nil
+}
+
+func (r *LoginAlertRepository) SetLastLogin(ctx context.Context, userID uint, last LastLogin, ttl time.Duration) error {
+	key := fmt.Sprintf("last_login:%d", userID)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key,
+		"at", last.At.Unix(),
+		"country", last.Country,
+		"lat", last.Latitude,
+		"lon", last.Longitude,
+	)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+/* ============================
+   Report tokens
+============================ */
+
+func loginReportKey(rowToken string) string {
+	return fmt.Sprintf("login_report:%s", hashToken(rowToken))
+}
+
+// StoreReport saves a token that lets the user report the login in
+// sessionID as not theirs.
+func (r *LoginAlertRepository) StoreReport(ctx context.Context, rowToken string, userID uint, sessionID string, ttl time.Duration) error {
+	key := loginReportKey(rowToken)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "session_id", sessionID)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// TakeReport returns and deletes a report token, so each link works once.
+// It returns 0 if the token does not exist.
+func (r *LoginAlertRepository) TakeReport(ctx context.Context, rowToken string) (uint, string, error) {
+	key := loginReportKey(rowToken)
+
+	pipe := r.rdb.TxPipeline()
+	data := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, "", err
+	}
+
+	fields := data.Val()
+	var userID uint
+	if _, err := fmt.Sscan(fields["user_id"], &userID); err != nil {
+		return 0, "", nil
+	}
+	return userID, fields["session_id"], nil
+}
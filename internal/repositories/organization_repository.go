@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+type OrgMemberInfo struct {
+	UserID   uint           `json:"user_id"`
+	Email    string         `json:"email"`
+	Role     models.OrgRole `json:"role"`
+	JoinedAt time.Time      `json:"joined_at"`
+}
+
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// Create inserts the organization and makes ownerID its first owner.
+func (r *OrganizationRepository) Create(org *models.Organization, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.Membership{
+			OrganizationID: org.ID,
+			UserID:         ownerID,
+			Role:           models.OrgOwner,
+		}).Error
+	})
+}
+
+func (r *OrganizationRepository) FindByID(orgID uint) (*models.Organization, error) {
+	var org models.Organization
+
+	err := r.db.Where("id = ?", orgID).First(&org).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepository) FindBySlug(slug string) (*models.Organization, error) {
+	var org models.Organization
+
+	err := r.db.Where("slug = ?", slug).First(&org).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepository) ListForUser(userID uint) ([]models.OrgMembership, error) {
+	var orgs []models.OrgMembership
+	err := r.db.
+		Table("memberships AS m").
+		Select("o.id AS organization_id, o.name, o.slug, m.role").
+		Joins("JOIN organizations o ON o.id = m.organization_id").
+		Where("m.user_id = ?", userID).
+		Order("m.created_at ASC").
+		Scan(&orgs).Error
+	if err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// GetMembership returns the user's membership in the organization, or nil if
+// the user is not a member.
+func (r *OrganizationRepository) GetMembership(orgID, userID uint) (*models.Membership, error) {
+	var membership models.Membership
+
+	err := r.db.
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+// ActiveMembership is GetMembership for acting in the organization: it
+// also returns nil if the membership is suspended.
+func (r *OrganizationRepository) ActiveMembership(orgID, userID uint) (*models.Membership, error) {
+	membership, err := r.GetMembership(orgID, userID)
+	if err != nil || membership == nil || membership.SuspendedAt != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// DefaultMembership returns the oldest unsuspended membership of the user,
+// used as the active organization at login.
+func (r *OrganizationRepository) DefaultMembership(userID uint) (*models.Membership, error) {
+	var membership models.Membership
+
+	err := r.db.
+		Where("user_id = ? AND suspended_at IS NULL", userID).
+		Order("created_at ASC").
+		First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+func (r *OrganizationRepository) AddMember(orgID, userID uint, role models.OrgRole) error {
+	return r.db.Create(&models.Membership{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	}).Error
+}
+
+func (r *OrganizationRepository) ListMembers(orgID uint) ([]OrgMemberInfo, error) {
+	var members []OrgMemberInfo
+	err := r.db.
+		Table("memberships AS m").
+		Select("u.id AS user_id, u.email, m.role, m.created_at AS joined_at").
+		Joins("JOIN users u ON u.id = m.user_id").
+		Where("m.organization_id = ?", orgID).
+		Order("m.created_at ASC").
+		Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *OrganizationRepository) UpdateMemberRole(orgID, userID uint, role models.OrgRole) error {
+	return r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Updates(map[string]any{"role": role, "updated_at": time.Now()}).Error
+}
+
+func (r *OrganizationRepository) RemoveMember(orgID, userID uint) error {
+	return r.db.
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Delete(&models.Membership{}).Error
+}
+
+func (r *OrganizationRepository) CountOwners(orgID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND role = ?", orgID, models.OrgOwner).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type OTPResult int
+
+const (
+	OTPValid OTPResult = iota
+	OTPInvalid
+	// OTPExpired means there is no pending code, either because it expired
+	// or because it was used up.
+	OTPExpired
+	OTPTooManyAttempts
+)
+
+// OTPRepository stores one-time passcodes and MFA login challenges in
+// Redis. Codes are kept hashed and bound to the user and purpose, so a code
+// sent to verify a phone cannot complete a login.
+type OTPRepository struct {
+	rdb *redis.Client
+}
+
+func NewOTPRepository(rdb *redis.Client) *OTPRepository {
+	return &OTPRepository{rdb: rdb}
+}
+
+func otpKey(purpose string, userID uint) string {
+	return fmt.Sprintf("sms_otp:%s:%d", purpose, userID)
+}
+
+func otpHash(purpose string, userID uint, code string) string {
+	return hashToken(fmt.Sprintf("%s:%d:%s", purpose, userID, code))
+}
+
+/* ============================
+   Codes
+============================ */
+
+// ClaimResend allows one code per user and purpose per interval. It reports
+// false if a code was sent too recently.
+func (r *OTPRepository) ClaimResend(ctx context.Context, purpose string, userID uint, interval time.Duration) (bool, error) {
+	key := fmt.Sprintf("sms_otp_resend:%s:%d", purpose, userID)
+	return r.rdb.SetNX(ctx, key, 1, interval).Result()
+}
+
+// CountSent increments and returns the number of codes sent to the user in
+// the current window.
+func (r *OTPRepository) CountSent(ctx context.Context, userID uint, window time.Duration) (int64, error) {
+	key := fmt.Sprintf("sms_otp_sent:%d", userID)
+
+	count, err := r.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		r.rdb.Expire(ctx, key, window)
+	}
+	return count, nil
+}
+
+// Store replaces any pending code for the user and purpose.
+func (r *OTPRepository) Store(ctx context.Context, purpose string, userID uint, code string, ttl time.Duration) error {
+	key := otpKey(purpose, userID)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "code_hash", otpHash(purpose, userID, code), "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Verify checks code against the pending one. Every call counts as an
+// attempt; the code is deleted once it matches or maxAttempts is reached.
+func (r *OTPRepository) Verify(ctx context.Context, purpose string, userID uint, code string, maxAttempts int) (OTPResult, error) {
+	key := otpKey(purpose, userID)
+
+	stored, err := r.rdb.HGet(ctx, key, "code_hash").Result()
+	if errors.Is(err, redis.Nil) {
+		return OTPExpired, nil
+	}
+	if err != nil {
+		return OTPInvalid, err
+	}
+
+	attempts, err := r.rdb.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return OTPInvalid, err
+	}
+	if attempts > int64(maxAttempts) {
+		r.rdb.Del(ctx, key)
+		return OTPTooManyAttempts, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(otpHash(purpose, userID, code))) == 1 {
+		r.rdb.Del(ctx, key)
+		return OTPValid, nil
+	}
+
+	if attempts == int64(maxAttempts) {
+		r.rdb.Del(ctx, key)
+	}
+	return OTPInvalid, nil
+}
+
+/* ============================
+   MFA login challenges
+============================ */
+
+func mfaChallengeKey(rowToken string) string {
+	return fmt.Sprintf("mfa_challenge:%s", hashToken(rowToken))
+}
+
+// CreateChallenge records that the user passed the password step and still
+// owes a second factor.
+func (r *OTPRepository) CreateChallenge(ctx context.Context, rowToken string, userID uint, ttl time.Duration) error {
+	return r.rdb.Set(ctx, mfaChallengeKey(rowToken), userID, ttl).Err()
+}
+
+// GetChallenge returns the challenge's user, or 0 if it does not exist.
+func (r *OTPRepository) GetChallenge(ctx context.Context, rowToken string) (uint, error) {
+	uid64, err := r.rdb.Get(ctx, mfaChallengeKey(rowToken)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint(uid64), nil
+}
+
+func (r *OTPRepository) DeleteChallenge(ctx context.Context, rowToken string) error {
+	return r.rdb.Del(ctx, mfaChallengeKey(rowToken)).Err()
+}
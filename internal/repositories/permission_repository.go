@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const permissionCacheTTL = 5 * time.Minute
+
+// PermissionRepository resolves roles and role permissions from Postgres and
+// caches the results in Redis so authorization checks stay cheap.
+type PermissionRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewPermissionRepository(db *gorm.DB, rdb *redis.Client) *PermissionRepository {
+	return &PermissionRepository{db: db, rdb: rdb}
+}
+
+/* ============================
+   User role lookup
+============================ */
+
+// GetUserRole returns the role of the given user, or "" if the user does not
+// exist or is not active (suspended, pending deletion or deleted).
+func (r *PermissionRepository) GetUserRole(ctx context.Context, userID uint) (string, error) {
+	key := fmt.Sprintf("authz:user_role:%d", userID)
+
+	role, err := r.rdb.Get(ctx, key).Result()
+	if err == nil {
+		return role, nil
+	}
+
+	var user models.UserModel
+	err = r.db.WithContext(ctx).Select("role", "status").Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	role = ""
+	if user.Status == models.StatusActive {
+		role = string(user.Role)
+	}
+	r.rdb.Set(ctx, key, role, permissionCacheTTL)
+
+	return role, nil
+}
+
+// GetServiceAccountRole returns the role of an enabled service account, or ""
+// if it does not exist or is disabled.
+func (r *PermissionRepository) GetServiceAccountRole(ctx context.Context, serviceAccountID uint) (string, error) {
+	key := fmt.Sprintf("authz:sa_role:%d", serviceAccountID)
+
+	role, err := r.rdb.Get(ctx, key).Result()
+	if err == nil {
+		return role, nil
+	}
+
+	var sa models.ServiceAccount
+	err = r.db.WithContext(ctx).
+		Select("role").
+		Where("id = ? AND disabled_at IS NULL", serviceAccountID).
+		First(&sa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	role = string(sa.Role)
+	r.rdb.Set(ctx, key, role, permissionCacheTTL)
+
+	return role, nil
+}
+
+/* ============================
+   Role permissions lookup
+============================ */
+
+func (r *PermissionRepository) GetRolePermissions(ctx context.Context, role string) ([]models.RolePermission, error) {
+	key := fmt.Sprintf("authz:role_perms:%s", role)
+
+	if cached, err := r.rdb.Get(ctx, key).Bytes(); err == nil {
+		var perms []models.RolePermission
+		if err := json.Unmarshal(cached, &perms); err == nil {
+			return perms, nil
+		}
+	}
+
+	var perms []models.RolePermission
+	err := r.db.WithContext(ctx).Where("role = ?", role).Find(&perms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, err := json.Marshal(perms); err == nil {
+		r.rdb.Set(ctx, key, raw, permissionCacheTTL)
+	}
+
+	return perms, nil
+}
+
+/* ============================
+   Cache invalidation
+============================ */
+
+func (r *PermissionRepository) InvalidateUser(ctx context.Context, userID uint) error {
+	return r.rdb.Del(ctx, fmt.Sprintf("authz:user_role:%d", userID)).Err()
+}
+
+func (r *PermissionRepository) InvalidateServiceAccount(ctx context.Context, serviceAccountID uint) error {
+	return r.rdb.Del(ctx, fmt.Sprintf("authz:sa_role:%d", serviceAccountID)).Err()
+}
+
+func (r *PermissionRepository) InvalidateRole(ctx context.Context, role string) error {
+	return r.rdb.Del(ctx, fmt.Sprintf("authz:role_perms:%s", role)).Err()
+}
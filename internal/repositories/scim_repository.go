@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// SCIMUser is a user as seen by one organization's SCIM client: the account
+// plus the organization-scoped external ID, role and suspension.
+type SCIMUser struct {
+	models.UserModel
+	ExternalID  *string
+	OrgRole     models.OrgRole
+	SuspendedAt *time.Time
+}
+
+// SCIMCondition is one comparison of a SCIM filter, already mapped to a
+// field of SCIMUser. Op is a SCIM operator (eq, ne, co, sw, ew, pr). The
+// "active" field compares "true" or "false" with eq or ne.
+type SCIMCondition struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// scimColumns whitelists the fields SCIM filters may reference.
+var scimColumns = map[string]string{
+	"id":          "users.id",
+	"email":       "users.email",
+	"active":      "memberships.suspended_at",
+	"external_id": "memberships.external_id",
+	"org_role":    "memberships.role",
+}
+
+type SCIMRepository struct {
+	db *gorm.DB
+}
+
+func NewSCIMRepository(db *gorm.DB) *SCIMRepository {
+	return &SCIMRepository{db: db}
+}
+
+/* ============================
+   Tokens
+============================ */
+
+// CreateToken stores the token with only the hash of rowToken.
+func (r *SCIMRepository) CreateToken(token *models.SCIMToken, rowToken string) error {
+	token.TokenHash = hashToken(rowToken)
+	return r.db.Create(token).Error
+}
+
+func (r *SCIMRepository) ListTokens(orgID uint) ([]models.SCIMToken, error) {
+	var tokens []models.SCIMToken
+	err := r.db.
+		Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// FindActiveToken returns the unrevoked token matching rowToken, or nil.
+func (r *SCIMRepository) FindActiveToken(rowToken string) (*models.SCIMToken, error) {
+	var token models.SCIMToken
+
+	err := r.db.
+		Where("token_hash = ? AND revoked_at IS NULL", hashToken(rowToken)).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *SCIMRepository) RevokeToken(orgID, tokenID uint) (bool, error) {
+	res := r.db.Model(&models.SCIMToken{}).
+		Where("id = ? AND organization_id = ? AND revoked_at IS NULL", tokenID, orgID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *SCIMRepository) TouchToken(tokenID uint) {
+	go r.db.Model(&models.SCIMToken{}).
+		Where("id = ?", tokenID).
+		Update("last_used_at", time.Now())
+}
+
+/* ============================
+   Users
+============================ */
+
+func (r *SCIMRepository) usersIn(orgID uint) *gorm.DB {
+	return r.db.Model(&models.UserModel{}).
+		Joins("JOIN memberships ON memberships.user_id = users.id").
+		Where("memberships.organization_id = ?", orgID)
+}
+
+func applySCIMCondition(db *gorm.DB, cond SCIMCondition) (*gorm.DB, error) {
+	column, ok := scimColumns[cond.Field]
+	if !ok {
+		return nil, ErrInvalidFilter
+	}
+
+	if cond.Field == "id" {
+		if cond.Op != "eq" && cond.Op != "ne" && cond.Op != "pr" {
+			return nil, ErrInvalidFilter
+		}
+		if cond.Op != "pr" {
+			if _, err := strconv.ParseUint(cond.Value, 10, 32); err != nil {
+				// no user can match a non-numeric id
+				if cond.Op == "eq" {
+					return db.Where("FALSE"), nil
+				}
+				return db, nil
+			}
+		}
+	}
+
+	if cond.Field == "active" {
+		if cond.Op != "eq" && cond.Op != "ne" {
+			return nil, ErrInvalidFilter
+		}
+		if (cond.Value == "true") == (cond.Op == "eq") {
+			return db.Where(column + " IS NULL"), nil
+		}
+		return db.Where(column + " IS NOT NULL"), nil
+	}
+
+	like := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(cond.Value)
+
+	switch cond.Op {
+	case "eq":
+		return db.Where(column+" = ?", cond.Value), nil
+	case "ne":
+		return db.Where(column+" IS DISTINCT FROM ?", cond.Value), nil
+	case "co":
+		return db.Where(column+" LIKE ?", "%"+like+"%"), nil
+	case "sw":
+		return db.Where(column+" LIKE ?", like+"%"), nil
+	case "ew":
+		return db.Where(column+" LIKE ?", "%"+like), nil
+	case "pr":
+		return db.Where(column + " IS NOT NULL"), nil
+	default:
+		return nil, ErrInvalidFilter
+	}
+}
+
+// ListUsers returns the organization's users matching every condition,
+// ordered by ID, with the total count before offset/limit are applied.
+func (r *SCIMRepository) ListUsers(orgID uint, conds []SCIMCondition, offset, limit int) ([]SCIMUser, int64, error) {
+	query := r.usersIn(orgID)
+	for _, cond := range conds {
+		var err error
+		if query, err = applySCIMCondition(query, cond); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]SCIMUser, 0)
+	if limit == 0 {
+		return users, total, nil
+	}
+
+	err := query.
+		Select("users.*, memberships.external_id, memberships.role AS org_role, memberships.suspended_at").
+		Order("users.id").
+		Offset(offset).
+		Limit(limit).
+		Scan(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// FindUser returns the user if it is a member of the organization, or nil.
+func (r *SCIMRepository) FindUser(orgID, userID uint) (*SCIMUser, error) {
+	var users []SCIMUser
+
+	err := r.usersIn(orgID).
+		Select("users.*, memberships.external_id, memberships.role AS org_role, memberships.suspended_at").
+		Where("users.id = ?", userID).
+		Limit(1).
+		Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	return &users[0], nil
+}
+
+// CreateMember creates the user, managed by the organization, and its
+// membership in one transaction.
+func (r *SCIMRepository) CreateMember(orgID uint, user *models.UserModel, externalID *string) error {
+	user.ManagedByOrgID = &orgID
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.Membership{
+			OrganizationID: orgID,
+			UserID:         user.ID,
+			Role:           models.OrgMember,
+			ExternalID:     externalID,
+		}).Error
+	})
+}
+
+func (r *SCIMRepository) SetExternalID(orgID, userID uint, externalID *string) error {
+	return r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Updates(map[string]any{"external_id": externalID, "updated_at": time.Now()}).Error
+}
+
+// SetSuspended suspends the user's membership (at non-nil) or lifts the
+// suspension (nil).
+func (r *SCIMRepository) SetSuspended(orgID, userID uint, at *time.Time) error {
+	return r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Updates(map[string]any{"suspended_at": at, "updated_at": time.Now()}).Error
+}
+
+func (r *SCIMRepository) UpdateEmail(userID uint, email string) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("email", email).Error
+}
+
+// ExternalIDTaken reports whether another member of the organization
+// already uses externalID.
+func (r *SCIMRepository) ExternalIDTaken(orgID, userID uint, externalID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).
+		Where("organization_id = ? AND external_id = ? AND user_id <> ?", orgID, externalID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
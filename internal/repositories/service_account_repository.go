@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"gorm.io/gorm"
+)
+
+type ServiceAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountRepository(db *gorm.DB) *ServiceAccountRepository {
+	return &ServiceAccountRepository{db: db}
+}
+
+// Create stores the account with only the hash of rowSecret.
+func (r *ServiceAccountRepository) Create(sa *models.ServiceAccount, rowSecret string) error {
+	sa.ClientSecretHash = hashToken(rowSecret)
+	return r.db.Create(sa).Error
+}
+
+func (r *ServiceAccountRepository) FindByID(id uint) (*models.ServiceAccount, error) {
+	var sa models.ServiceAccount
+
+	err := r.db.Where("id = ?", id).First(&sa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &sa, nil
+}
+
+func (r *ServiceAccountRepository) FindByClientID(clientID string) (*models.ServiceAccount, error) {
+	var sa models.ServiceAccount
+
+	err := r.db.Where("client_id = ?", clientID).First(&sa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &sa, nil
+}
+
+// List returns all service accounts, or only those of orgID when it is set.
+func (r *ServiceAccountRepository) List(orgID uint) ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+
+	query := r.db.Order("created_at DESC")
+	if orgID != 0 {
+		query = query.Where("organization_id = ?", orgID)
+	}
+
+	if err := query.Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *ServiceAccountRepository) MatchesSecret(sa *models.ServiceAccount, rowSecret string) bool {
+	if sa.ClientSecretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(rowSecret)), []byte(sa.ClientSecretHash)) == 1
+}
+
+func (r *ServiceAccountRepository) UpdateSecret(id uint, rowSecret string) error {
+	return r.db.Model(&models.ServiceAccount{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"client_secret_hash": hashToken(rowSecret),
+			"updated_at":         time.Now(),
+		}).Error
+}
+
+func (r *ServiceAccountRepository) SetDisabled(id uint, disabled bool) error {
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+
+	return r.db.Model(&models.ServiceAccount{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"disabled_at": disabledAt,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+func (r *ServiceAccountRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(&models.ServiceAccount{}).Error
+}
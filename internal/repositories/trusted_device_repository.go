@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
+	"github.com/redis/go-redis/v9"
+)
+
+// TrustedDevice is a browser the user chose to remember after passing a
+// second factor; logins from it skip the second factor until it expires or
+// is revoked.
+type TrustedDevice struct {
+	DeviceID   string `json:"device_id"`
+	UserID     uint   `json:"-"`
+	IP         string `json:"ip"`
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	// Fingerprint is checked against the device logging in, so a copied
+	// cookie does not work from a different browser.
+	Fingerprint string `json:"-"`
+}
+
+type TrustedDeviceRepository struct {
+	rdb *redis.Client
+}
+
+func NewTrustedDeviceRepository(rdb *redis.Client) *TrustedDeviceRepository {
+	return &TrustedDeviceRepository{rdb: rdb}
+}
+
+func trustedDeviceKey(deviceID string) string {
+	return fmt.Sprintf("trusted_device:%s", deviceID)
+}
+
+func userTrustedDevicesKey(userID uint) string {
+	return fmt.Sprintf("user_trusted_devices:%d", userID)
+}
+
+/* ============================
+   Create
+============================ */
+
+func (r *TrustedDeviceRepository) Create(
+	ctx context.Context,
+	deviceID string,
+	userID uint,
+	fingerprint string,
+	ip string,
+	userAgent string,
+	ttl time.Duration,
+) error {
+	now := time.Now()
+	device := useragent.Parse(userAgent)
+	key := trustedDeviceKey(deviceID)
+	userKey := userTrustedDevicesKey(userID)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key,
+		"user_id", userID,
+		"fingerprint", fingerprint,
+		"ip", ip,
+		"browser", device.Browser,
+		"os", device.OS,
+		"device_type", device.Device,
+		"created_at", now.Unix(),
+		"last_used_at", now.Unix(),
+		"expires_at", now.Add(ttl).Unix(),
+	)
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, userKey, deviceID)
+	pipe.Expire(ctx, userKey, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+/* ============================
+   Lookup
+============================ */
+
+// Get returns the device, or nil if it does not exist or has expired.
+func (r *TrustedDeviceRepository) Get(ctx context.Context, deviceID string) (*TrustedDevice, error) {
+	data, err := r.rdb.HGetAll(ctx, trustedDeviceKey(deviceID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return trustedDevice(deviceID, data), nil
+}
+
+func trustedDevice(deviceID string, data map[string]string) *TrustedDevice {
+	userID, _ := strconv.ParseUint(data["user_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(data["created_at"], 10, 64)
+	lastUsedAt, _ := strconv.ParseInt(data["last_used_at"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(data["expires_at"], 10, 64)
+
+	return &TrustedDevice{
+		DeviceID:    deviceID,
+		UserID:      uint(userID),
+		IP:          data["ip"],
+		Browser:     data["browser"],
+		OS:          data["os"],
+		DeviceType:  data["device_type"],
+		CreatedAt:   createdAt,
+		LastUsedAt:  lastUsedAt,
+		ExpiresAt:   expiresAt,
+		Fingerprint: data["fingerprint"],
+	}
+}
+
+// ListByUser returns the user's trusted devices, dropping expired ones
+// from the user's set.
+func (r *TrustedDeviceRepository) ListByUser(ctx context.Context, userID uint) ([]TrustedDevice, error) {
+	userKey := userTrustedDevicesKey(userID)
+
+	deviceIDs, err := r.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []TrustedDevice{}
+	for _, deviceID := range deviceIDs {
+		data, err := r.rdb.HGetAll(ctx, trustedDeviceKey(deviceID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			r.rdb.SRem(ctx, userKey, deviceID)
+			continue
+		}
+		devices = append(devices, *trustedDevice(deviceID, data))
+	}
+
+	return devices, nil
+}
+
+// Touch records a login from the device.
+func (r *TrustedDeviceRepository) Touch(ctx context.Context, deviceID, ip string) error {
+	return r.rdb.HSet(ctx, trustedDeviceKey(deviceID),
+		"last_used_at", time.Now().Unix(),
+		"ip", ip,
+	).Err()
+}
+
+/* ============================
+   Revoke
+============================ */
+
+func (r *TrustedDeviceRepository) Delete(ctx context.Context, deviceID string, userID uint) error {
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, trustedDeviceKey(deviceID))
+	pipe.SRem(ctx, userTrustedDevicesKey(userID), deviceID)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *TrustedDeviceRepository) DeleteAll(ctx context.Context, userID uint) error {
+	userKey := userTrustedDevicesKey(userID)
+
+	deviceIDs, err := r.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := r.rdb.TxPipeline()
+	for _, deviceID := range deviceIDs {
+		pipe.Del(ctx, trustedDeviceKey(deviceID))
+	}
+	pipe.Del(ctx, userKey)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -1,12 +1,21 @@
 package repositories
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
 type UserRepository struct {
 	db *gorm.DB
 }
@@ -34,26 +43,442 @@ func (r *UserRepository) FindByEmail(email string) (*models.UserModel, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetAllUsers() ([]models.UserModel, error) {
-	var users []models.UserModel
-	err := r.db.Find(&users).Error
+// FindByUsername matches case-insensitively, like the unique index.
+func (r *UserRepository) FindByUsername(username string) (*models.UserModel, error) {
+	var user models.UserModel
+
+	err := r.db.Where("lower(username) = lower(?)", username).First(&user).Error
+
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	return users, nil
+
+	return &user, nil
 }
 
-func (r *UserRepository) GetAllAdmins() ([]models.UserModel, error) {
-	var admins []models.UserModel
-	err := r.db.
-		Where("role = ?", "admin").
-		Find(&admins).Error
+// FindByPhone looks up an E.164 phone number.
+func (r *UserRepository) FindByPhone(phone string) (*models.UserModel, error) {
+	var user models.UserModel
+
+	err := r.db.Where("phone = ?", phone).First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) FindByID(userID uint) (*models.UserModel, error) {
+	var user models.UserModel
+
+	err := r.db.Where("id = ?", userID).First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// inOrg scopes a users query to members of the given organization.
+func inOrg(orgID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("JOIN memberships ON memberships.user_id = users.id").
+			Where("memberships.organization_id = ?", orgID)
+	}
+}
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// userSortColumns whitelists the columns users can be sorted by.
+var userSortColumns = map[string]string{
+	"id":         "users.id",
+	"email":      "users.email",
+	"created_at": "users.created_at",
+}
+
+// UserFilter narrows a user listing. OrgID limits it to members of that
+// organization; 0 lists users of every organization.
+type UserFilter struct {
+	OrgID         uint
+	Role          string
+	Status        string
+	EmailPrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Desc          bool
+	Limit         int
+	Cursor        string
+}
+
+type UserPage struct {
+	Users      []models.UserModel `json:"users"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Total      int64              `json:"total"`
+}
+
+// userCursor is the keyset position after the last user of a page: the
+// value of the sort column plus the ID as tie breaker.
+type userCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeUserCursor(cur userCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeUserCursor(s string) (*userCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cur userCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
+
+// sortValue is the cursor's value as the type of the sort column.
+func (cur *userCursor) sortValue(sort string) (any, error) {
+	if sort != "created_at" {
+		return cur.Value, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, cur.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Model(&models.UserModel{})
+	if f.OrgID != 0 {
+		db = db.Scopes(inOrg(f.OrgID))
+	}
+
+	if f.Role != "" {
+		db = db.Where("users.role = ?", f.Role)
+	}
+	if f.Status != "" {
+		db = db.Where("users.status = ?", f.Status)
+	}
+	if f.EmailPrefix != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(f.EmailPrefix))
+		db = db.Where("users.email LIKE ?", escaped+"%")
+	}
+	if f.CreatedAfter != nil {
+		db = db.Where("users.created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		db = db.Where("users.created_at < ?", *f.CreatedBefore)
+	}
+
+	return db
+}
+
+// ListUsers returns one page of users matching the filter using
+// keyset pagination, so deep pages stay cheap and stable under inserts.
+func (r *UserRepository) ListUsers(f UserFilter) (*UserPage, error) {
+	sort := f.Sort
+	if sort == "" {
+		sort = "created_at"
+	}
+	column, ok := userSortColumns[sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	if limit > maxUserPageSize {
+		limit = maxUserPageSize
+	}
+
+	page := &UserPage{Users: make([]models.UserModel, 0)}
+	if err := f.apply(r.db).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, cmp := "ASC", ">"
+	if f.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	query := f.apply(r.db).Select("users.*")
+
+	if f.Cursor != "" {
+		cur, err := decodeUserCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if sort == "id" {
+			query = query.Where("users.id "+cmp+" ?", cur.ID)
+		} else {
+			value, err := cur.sortValue(sort)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("("+column+", users.id) "+cmp+" (?, ?)", value, cur.ID)
+		}
+	}
+
+	orderBy := column + " " + direction
+	if sort != "id" {
+		orderBy += ", users.id " + direction
+	}
+
+	// fetch one extra row to know whether there is a next page
+	err := query.Order(orderBy).Limit(limit + 1).Find(&page.Users).Error
 	if err != nil {
 		return nil, err
 	}
-	return admins, nil
+
+	if len(page.Users) > limit {
+		page.Users = page.Users[:limit]
+		last := page.Users[limit-1]
+
+		cur := userCursor{ID: last.ID}
+		switch sort {
+		case "email":
+			cur.Value = last.Email
+		case "created_at":
+			cur.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		page.NextCursor = encodeUserCursor(cur)
+	}
+
+	return page, nil
 }
 
 func (r *UserRepository) UpdatePassword(userID uint, hashPassword string) error {
-	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("password", hashPassword).Error
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Updates(map[string]any{
+		"password":            hashPassword,
+		"must_reset_password": false,
+	}).Error
+}
+
+func (r *UserRepository) UpdateRole(userID uint, role models.UserRole) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+func (r *UserRepository) UpdateStatus(userID uint, status models.UserStatus) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("status", status).Error
+}
+
+func (r *UserRepository) SetMustResetPassword(userID uint, mustReset bool) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("must_reset_password", mustReset).Error
+}
+
+// UpdateProfile sets the given profile columns. Callers pass only the
+// columns they validated.
+func (r *UserRepository) UpdateProfile(userID uint, fields map[string]any) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Updates(fields).Error
+}
+
+// MarkPhoneVerified verifies phone for the user unless the number changed
+// since the code was sent.
+func (r *UserRepository) MarkPhoneVerified(userID uint, phone string, at time.Time) (bool, error) {
+	res := r.db.Model(&models.UserModel{}).
+		Where("id = ? AND phone = ?", userID, phone).
+		Update("phone_verified_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *UserRepository) SetSMSMFA(userID uint, enabled bool) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("mfa_sms_enabled", enabled).Error
+}
+
+func (r *UserRepository) UpdateAppMetadata(userID uint, metadata []byte) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("app_metadata", string(metadata)).Error
+}
+
+func (r *UserRepository) Delete(userID uint) error {
+	return r.db.Where("id = ?", userID).Delete(&models.UserModel{}).Error
+}
+
+/* ============================
+   Bulk import / export
+============================ */
+
+// ExistingEmails returns which of the given emails already have an account.
+func (r *UserRepository) ExistingEmails(emails []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(emails))
+	if len(emails) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.Model(&models.UserModel{}).Where("email IN ?", emails).Pluck("email", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, email := range found {
+		existing[email] = true
+	}
+	return existing, nil
+}
+
+// CreateBatch inserts users, and memberships in orgID when it is non-zero,
+// in a single transaction so a chunk is imported entirely or not at all.
+func (r *UserRepository) CreateBatch(users []models.UserModel, orgID uint) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&users).Error; err != nil {
+			return err
+		}
+
+		if orgID == 0 {
+			return nil
+		}
+
+		memberships := make([]models.Membership, 0, len(users))
+		for _, user := range users {
+			memberships = append(memberships, models.Membership{
+				OrganizationID: orgID,
+				UserID:         user.ID,
+				Role:           models.OrgMember,
+			})
+		}
+		return tx.Create(&memberships).Error
+	})
+}
+
+// StreamUsers calls fn with users in ID order, batchSize at a time, so
+// exports never hold the whole table in memory. orgID 0 streams every user.
+func (r *UserRepository) StreamUsers(orgID uint, batchSize int, fn func([]models.UserModel) error) error {
+	query := r.db.Model(&models.UserModel{})
+	if orgID != 0 {
+		query = query.Scopes(inOrg(orgID)).Select("users.*")
+	}
+
+	var batch []models.UserModel
+	return query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+/* ============================
+   Account deletion
+============================ */
+
+// ScheduleDeletion marks an active account pending deletion at the given
+// time. It reports false if the account was not active.
+func (r *UserRepository) ScheduleDeletion(userID uint, at time.Time) (bool, error) {
+	res := r.db.Model(&models.UserModel{}).
+		Where("id = ? AND status = ?", userID, models.StatusActive).
+		Updates(map[string]any{
+			"status":                models.StatusPendingDeletion,
+			"deletion_scheduled_at": at,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// CancelDeletion restores a pending-deletion account to active.
+func (r *UserRepository) CancelDeletion(userID uint) (bool, error) {
+	res := r.db.Model(&models.UserModel{}).
+		Where("id = ? AND status = ?", userID, models.StatusPendingDeletion).
+		Updates(map[string]any{
+			"status":                models.StatusActive,
+			"deletion_scheduled_at": nil,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// DueForDeletion returns up to limit accounts whose grace period is over.
+func (r *UserRepository) DueForDeletion(now time.Time, limit int) ([]models.UserModel, error) {
+	var users []models.UserModel
+	err := r.db.
+		Where("status = ? AND deletion_scheduled_at <= ?", models.StatusPendingDeletion, now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// dueForDeletion re-checks the schedule inside the write so a cancellation
+// racing with the purge job always wins.
+func dueForDeletion(db *gorm.DB, userID uint, now time.Time) *gorm.DB {
+	return db.Where("id = ? AND status = ? AND deletion_scheduled_at <= ?", userID, models.StatusPendingDeletion, now)
+}
+
+// PurgeDeleted hard deletes a due account. Memberships and API keys cascade
+// and audit_logs.user_id is set to NULL by the foreign key.
+func (r *UserRepository) PurgeDeleted(userID uint, now time.Time) (bool, error) {
+	res := dueForDeletion(r.db, userID, now).Delete(&models.UserModel{})
+	return res.RowsAffected == 1, res.Error
+}
+
+// AnonymizeDeleted keeps the row of a due account but strips everything that
+// identifies the person, mirroring what a hard delete would remove.
+func (r *UserRepository) AnonymizeDeleted(userID uint, email, password string, now time.Time) (bool, error) {
+	anonymized := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := dueForDeletion(tx.Model(&models.UserModel{}), userID, now).Updates(map[string]any{
+			"email":                 email,
+			"password":              password,
+			"role":                  models.User,
+			"status":                models.StatusDeleted,
+			"must_reset_password":   false,
+			"deletion_scheduled_at": nil,
+			"username":              nil,
+			"phone":                 nil,
+			"phone_verified_at":     nil,
+			"mfa_sms_enabled":       false,
+			"display_name":          "",
+			"locale":                "",
+			"timezone":              "",
+			"avatar_url":            "",
+			"app_metadata":          gorm.Expr("'{}'::jsonb"),
+			"user_metadata":         gorm.Expr("'{}'::jsonb"),
+		})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AuditLog{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+			return err
+		}
+
+		anonymized = true
+		return nil
+	})
+
+	return anonymized, err
 }
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUserCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cur  userCursor
+	}{
+		{name: "id only", cur: userCursor{ID: 42}},
+		{name: "email", cur: userCursor{Value: "alice@example.com", ID: 7}},
+		{name: "created_at", cur: userCursor{Value: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC).Format(time.RFC3339Nano), ID: 9}},
+		{name: "characters json escapes", cur: userCursor{Value: `"quoted" <tag> & \back`, ID: 1}},
+		{name: "non-ascii", cur: userCursor{Value: "jürgen@example.com", ID: 3}},
+		{name: "max id", cur: userCursor{Value: "x", ID: ^uint(0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeUserCursor(tt.cur)
+
+			// cursors travel in query strings
+			if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				t.Fatalf("cursor %q is not unpadded base64url: %v", encoded, err)
+			}
+
+			got, err := decodeUserCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeUserCursor(%q): %v", encoded, err)
+			}
+			if *got != tt.cur {
+				t.Errorf("round trip = %+v, want %+v", *got, tt.cur)
+			}
+		})
+	}
+}
+
+func TestDecodeUserCursorRejectsTampering(t *testing.T) {
+	valid := encodeUserCursor(userCursor{Value: "alice@example.com", ID: 7})
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded standard base64", cursor: base64.StdEncoding.EncodeToString([]byte(`{"v":"a","id":1}`)) + "="},
+		{name: "truncated", cursor: valid[:len(valid)-3]},
+		{name: "extra bytes", cursor: valid + "AA"},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("v=a&id=1"))},
+		{name: "id is a string", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"v":"a","id":"1"}`))},
+		{name: "negative id", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"v":"a","id":-1}`))},
+		{name: "value is a number", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"v":5,"id":1}`))},
+		{name: "sql in id", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"v":"a","id":"1 OR 1=1"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeUserCursor(tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeUserCursor(%q) = %+v, %v; want ErrInvalidCursor", tt.cursor, got, err)
+			}
+		})
+	}
+}
+
+func TestUserCursorSortValue(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	t.Run("created_at", func(t *testing.T) {
+		cur := userCursor{Value: created.Format(time.RFC3339Nano), ID: 1}
+		got, err := cur.sortValue("created_at")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts, ok := got.(time.Time); !ok || !ts.Equal(created) {
+			t.Errorf("sortValue = %v, want %v", got, created)
+		}
+	})
+
+	t.Run("email", func(t *testing.T) {
+		cur := userCursor{Value: "alice@example.com", ID: 1}
+		got, err := cur.sortValue("email")
+		if err != nil || got != "alice@example.com" {
+			t.Errorf("sortValue = %v, %v; want alice@example.com", got, err)
+		}
+	})
+
+	// an email cursor replayed against the created_at sort, or a hand
+	// edited timestamp, must not reach the query
+	for _, value := range []string{"alice@example.com", "", "2024-03-01", "2024-03-01 12:30:00", "yesterday"} {
+		t.Run("created_at rejects "+value, func(t *testing.T) {
+			cur := userCursor{Value: value, ID: 1}
+			if got, err := cur.sortValue("created_at"); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("sortValue(%q) = %v, %v; want ErrInvalidCursor", value, got, err)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package router
 
 import (
+	"context"
 	"time"
 
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/handler"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/iplist"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/services"
@@ -12,7 +14,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func Register(app *fiber.App, db *gorm.DB, jwtCfg config.JWTConfig, sessionRepo *repositories.SessionRepository, rateLimiter *security.Ratelimiter, auditRepo *repositories.AuditRepo, passwordResetRepo *repositories.PasswordResetRepository) {
+func Register(app *fiber.App, db *gorm.DB, jwtCfg config.JWTConfig, sessionRepo *repositories.SessionRepository, rateLimiter *security.Ratelimiter, auditRepo *repositories.AuditRepo, passwordResetRepo *repositories.PasswordResetRepository, permissionRepo *repositories.PermissionRepository, sessionCfg config.SessionConfig, accountCfg config.AccountConfig, smsSender services.SMSSender, mailer services.Mailer, publicURL, frontendURL string, riskCfg config.RiskConfig, ipBlocklist *iplist.List) {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		sqlDB, _ := db.DB()
 		if err := sqlDB.Ping(); err != nil {
@@ -26,8 +28,23 @@ func Register(app *fiber.App, db *gorm.DB, jwtCfg config.JWTConfig, sessionRepo
 	})
 
 	userRepo := repositories.NewUserRepository(db)
-	userService := services.NewAuthService(userRepo, jwtCfg, sessionRepo, auditRepo, passwordResetRepo)
+	orgRepo := repositories.NewOrganizationRepository(db)
+	trustedDeviceRepo := repositories.NewTrustedDeviceRepository(sessionRepo.Redis())
+	userService := services.NewAuthService(userRepo, jwtCfg, sessionCfg, sessionRepo, trustedDeviceRepo, auditRepo, passwordResetRepo, orgRepo, mailer, frontendURL)
 	authHandler := handler.NewAuthHandler(userService)
+	otpRepo := repositories.NewOTPRepository(sessionRepo.Redis())
+	mfaService := services.NewMFAService(userRepo, otpRepo, smsSender, auditRepo, userService)
+	userService.SetMFA(mfaService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	loginAlertRepo := repositories.NewLoginAlertRepository(sessionRepo.Redis())
+	loginAlertService := services.NewLoginAlertService(loginAlertRepo, userRepo, sessionRepo, auditRepo, mailer, userService, publicURL)
+	userService.SetLoginAlerts(loginAlertService)
+	loginAlertHandler := handler.NewLoginAlertHandler(loginAlertService)
+	riskService := services.NewRiskService(loginAlertRepo, sessionRepo.GeoIP(), ipBlocklist, auditRepo, riskCfg)
+	userService.SetRisk(riskService)
+	inviteRepo := repositories.NewInvitationRepository(db)
+	orgService := services.NewOrgService(orgRepo, inviteRepo, userRepo, userService, auditRepo, mailer, frontendURL)
+	orgHandler := handler.NewOrgHandler(orgService)
 
 	auth := app.Group("/auth")
 
@@ -37,21 +54,163 @@ func Register(app *fiber.App, db *gorm.DB, jwtCfg config.JWTConfig, sessionRepo
 	auth.Post("/login", rateLimiter.Limit("login", 5, time.Minute, func(ip, ua string) {
 		auditRepo.Log("RATE_LIMIT_HIT", nil, ip, ua)
 	}), authHandler.Login)
+	auth.Post("/login/mfa", rateLimiter.Limit("login_mfa", 10, time.Minute, func(ip, ua string) {
+		auditRepo.Log("MFA_RATE_LIMIT", nil, ip, ua)
+	}), mfaHandler.VerifyLogin)
+	auth.Post("/login/mfa/resend", rateLimiter.Limit("login_mfa_resend", 3, time.Minute, func(ip, ua string) {
+		auditRepo.Log("MFA_RESEND_RATE_LIMIT", nil, ip, ua)
+	}), mfaHandler.ResendLogin)
 	auth.Post("/refresh", rateLimiter.Limit("refresh", 5, time.Minute, func(ip, ua string) {
 		auditRepo.Log("REFRESH_RATE_LIMIT", nil, ip, ua)
 	}), authHandler.Refresh)
 	// auth.Post("/logout", authHandler.Logout)
 	auth.Post("/reset-password", authHandler.PasswordReset)
 	auth.Patch("/reset-password/confirm", authHandler.PasswordResetConfirm)
+	auth.Get("/login-alerts/report", loginAlertHandler.ConfirmReport)
+	auth.Post("/login-alerts/report", rateLimiter.Limit("login_report", 5, time.Minute, func(ip, ua string) {
+		auditRepo.Log("LOGIN_REPORT_RATE_LIMIT", nil, ip, ua)
+	}), loginAlertHandler.Report)
+	auth.Post("/invitations/accept", rateLimiter.Limit("invite_accept", 5, time.Minute, func(ip, ua string) {
+		auditRepo.Log("INVITE_RATE_LIMIT", nil, ip, ua)
+	}), orgHandler.AcceptInvitation)
 
-	protected := auth.Group("/", security.JWT(jwtCfg.AccessSecret), security.CSRF())
-	protected.Get("/userlist", authHandler.UserList)
-	protected.Get("/sessions", authHandler.ListSessions)
-	protected.Delete("/sessions/:sessionID", authHandler.LogoutSession)
-	protected.Post("/logout-all", authHandler.LogoutAllSession)
-	protected.Post("/logout", authHandler.Logout)
+	accountDeletionService := services.NewAccountDeletionService(userRepo, orgRepo, sessionRepo, permissionRepo, auditRepo, accountCfg)
+	dataExportRepo := repositories.NewDataExportRepository(sessionRepo.Redis())
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	dataExportService := services.NewDataExportService(dataExportRepo, userRepo, orgRepo, apiKeyRepo, sessionRepo, auditRepo, mailer, publicURL)
+	accountHandler := handler.NewAccountHandler(accountDeletionService, dataExportService)
+	auth.Post("/account/delete/cancel", rateLimiter.Limit("deletion_cancel", 5, time.Minute, func(ip, ua string) {
+		auditRepo.Log("DELETION_CANCEL_RATE_LIMIT", nil, ip, ua)
+	}), accountHandler.CancelDeletion)
+	auth.Get("/account/export/download", rateLimiter.Limit("export_download", 10, time.Minute, func(ip, ua string) {
+		auditRepo.Log("EXPORT_DOWNLOAD_RATE_LIMIT", nil, ip, ua)
+	}), accountHandler.DownloadExport)
 
-	admin := protected.Group("/admin", security.RequiredRole("admin"))
+	// accounts past their deletion grace period are purged in the background
+	go accountDeletionService.RunDeletionWorker(context.Background(), accountCfg.DeletionSweepInterval)
+
+	serviceAccountRepo := repositories.NewServiceAccountRepository(db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, orgRepo, serviceAccountRepo, auditRepo)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	serviceAccountService := services.NewServiceAccountService(serviceAccountRepo, apiKeyRepo, apiKeyService, orgRepo, permissionRepo, auditRepo, jwtCfg)
+	serviceAccountHandler := handler.NewServiceAccountHandler(serviceAccountService)
+
+	auth.Post("/token", rateLimiter.Limit("token", 30, time.Minute, func(ip, ua string) {
+		auditRepo.Log("TOKEN_RATE_LIMIT", nil, ip, ua)
+	}), serviceAccountHandler.Token)
+
+	protected := auth.Group("/",
+		security.Authenticate(jwtCfg.AccessSecret, apiKeyService.Verify, serviceAccountService.IsActive),
+		security.ImpersonationGuard(userService.IsImpersonationActive),
+		security.CSRF(),
+	)
+	profileService := services.NewProfileService(userRepo, auditRepo)
+	profileHandler := handler.NewProfileHandler(profileService)
+	protected.Get("/me", security.RequireUser(), security.RequireScope("profile:read"), profileHandler.GetMe)
+	protected.Patch("/me", security.RequireUser(), security.BlockImpersonation(), security.RequireScope("profile:write"), profileHandler.UpdateMe)
+	protected.Post("/reauthenticate", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), rateLimiter.Limit("reauthenticate", 5, time.Minute, func(ip, ua string) {
+		auditRepo.Log("REAUTH_RATE_LIMIT", nil, ip, ua)
+	}), authHandler.Reauthenticate)
+	protected.Post("/phone/verify", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), mfaHandler.StartPhoneVerification)
+	protected.Post("/phone/verify/confirm", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), mfaHandler.ConfirmPhone)
+	protected.Post("/mfa/sms/enable", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), security.RequireRecentAuth(5*time.Minute), mfaHandler.EnableSMS)
+	protected.Post("/mfa/sms/disable", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), security.RequireRecentAuth(5*time.Minute), mfaHandler.DisableSMS)
+	protected.Get("/userlist", security.RequireScope("users:read"), authHandler.UserList)
+	protected.Get("/sessions", security.RequireUser(), security.RequireScope("sessions:read"), authHandler.ListSessions)
+	protected.Patch("/sessions/:sessionID", security.RequireUser(), security.BlockImpersonation(), security.RequireScope("sessions:write"), authHandler.RenameSession)
+	protected.Delete("/sessions/:sessionID", security.RequireUser(), security.RequireScope("sessions:write"), authHandler.LogoutSession)
+	protected.Delete("/trusted-devices/:deviceID", security.RequireUser(), security.BlockImpersonation(), security.RequireScope("sessions:write"), authHandler.RevokeTrustedDevice)
+	protected.Post("/logout-all", security.RequireUser(), security.BlockImpersonation(), security.RequireScope("sessions:write"), authHandler.LogoutAllSession)
+	protected.Post("/logout", security.RequireUser(), authHandler.Logout)
+	protected.Post("/impersonation/stop", security.RequireImpersonation(), authHandler.StopImpersonation)
+	protected.Post("/account/delete", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), security.RequireRecentAuth(5*time.Minute), accountHandler.RequestDeletion)
+	protected.Post("/account/export", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation(), accountHandler.RequestExport)
+	protected.Get("/account/export/:exportID", security.RequireUser(), security.DenyAPIKey(), accountHandler.GetExport)
+
+	orgs := protected.Group("/orgs", security.RequireUser())
+	orgs.Post("/", security.RequireScope("orgs:write"), orgHandler.CreateOrg)
+	orgs.Get("/", security.RequireScope("orgs:read"), orgHandler.ListOrgs)
+	orgs.Post("/switch", security.DenyAPIKey(), authHandler.SwitchOrg)
+	orgs.Get("/:orgID/members", security.RequireScope("orgs:read"), orgHandler.ListMembers)
+	orgs.Patch("/:orgID/members/:userID", security.RequireScope("orgs:write"), orgHandler.UpdateMemberRole)
+	orgs.Delete("/:orgID/members/:userID", security.RequireScope("orgs:write"), orgHandler.RemoveMember)
+	orgs.Post("/:orgID/invitations", security.RequireScope("orgs:write"), orgHandler.CreateInvitation)
+	orgs.Get("/:orgID/invitations", security.RequireScope("orgs:read"), orgHandler.ListInvitations)
+	orgs.Delete("/:orgID/invitations/:inviteID", security.RequireScope("orgs:write"), orgHandler.RevokeInvitation)
+
+	apiKeys := protected.Group("/api-keys", security.RequireUser(), security.DenyAPIKey(), security.BlockImpersonation())
+	apiKeys.Post("/", apiKeyHandler.Create)
+	apiKeys.Get("/", apiKeyHandler.List)
+	apiKeys.Delete("/:keyID", apiKeyHandler.Revoke)
+
+	admin := protected.Group("/admin", security.RequireUser(), security.RequiredRole("admin"), security.RequireScope("admin"), security.BlockImpersonation())
 	admin.Get("/adminlist", authHandler.AdminUserList)
+	admin.Post("/impersonate/:userID", security.DenyAPIKey(), authHandler.StartImpersonation)
+
+	adminService := services.NewAdminService(userRepo, sessionRepo, permissionRepo, auditRepo, userService)
+	adminHandler := handler.NewAdminHandler(adminService)
+
+	userImportService := services.NewUserImportService(userRepo, orgRepo, userService, auditRepo)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
+
+	adminUsers := admin.Group("/users")
+	adminUsers.Post("/import", userImportHandler.Import)
+	adminUsers.Get("/export", userImportHandler.Export)
+	adminUsers.Get("/:userID", adminHandler.GetUser)
+	adminUsers.Patch("/:userID/role", adminHandler.UpdateRole)
+	adminUsers.Put("/:userID/app-metadata", adminHandler.SetAppMetadata)
+	adminUsers.Post("/:userID/suspend", adminHandler.Suspend)
+	adminUsers.Post("/:userID/unsuspend", adminHandler.Unsuspend)
+	adminUsers.Post("/:userID/force-password-reset", adminHandler.ForcePasswordReset)
+	adminUsers.Delete("/:userID", adminHandler.DeleteUser)
+
+	serviceAccounts := admin.Group("/service-accounts")
+	serviceAccounts.Post("/", serviceAccountHandler.Create)
+	serviceAccounts.Get("/", serviceAccountHandler.List)
+	serviceAccounts.Get("/:serviceAccountID", serviceAccountHandler.Get)
+	serviceAccounts.Delete("/:serviceAccountID", serviceAccountHandler.Delete)
+	serviceAccounts.Post("/:serviceAccountID/secret", serviceAccountHandler.RotateSecret)
+	serviceAccounts.Post("/:serviceAccountID/disable", serviceAccountHandler.Disable)
+	serviceAccounts.Post("/:serviceAccountID/enable", serviceAccountHandler.Enable)
+	serviceAccounts.Post("/:serviceAccountID/api-keys", serviceAccountHandler.CreateAPIKey)
+	serviceAccounts.Get("/:serviceAccountID/api-keys", serviceAccountHandler.ListAPIKeys)
+	serviceAccounts.Delete("/:serviceAccountID/api-keys/:keyID", serviceAccountHandler.RevokeAPIKey)
+
+	scimRepo := repositories.NewSCIMRepository(db)
+	scimService := services.NewSCIMService(scimRepo, userRepo, orgRepo, sessionRepo, auditRepo)
+	scimHandler := handler.NewSCIMHandler(scimService)
+
+	scimTokens := admin.Group("/scim-tokens")
+	scimTokens.Post("/", scimHandler.CreateToken)
+	scimTokens.Get("/", scimHandler.ListTokens)
+	scimTokens.Delete("/:tokenID", scimHandler.RevokeToken)
+
+	scim := app.Group("/scim/v2", security.SCIMAuth(scimService.VerifyToken))
+	scim.Get("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+	scim.Get("/Users", scimHandler.ListUsers)
+	scim.Post("/Users", scimHandler.CreateUser)
+	scim.Get("/Users/:id", scimHandler.GetUser)
+	scim.Put("/Users/:id", scimHandler.ReplaceUser)
+	scim.Patch("/Users/:id", scimHandler.PatchUser)
+	scim.Delete("/Users/:id", scimHandler.DeleteUser)
+	scim.Get("/Groups", scimHandler.ListGroups)
+	scim.Post("/Groups", scimHandler.UnsupportedGroupOp)
+	scim.Get("/Groups/:id", scimHandler.GetGroup)
+	scim.Put("/Groups/:id", scimHandler.ReplaceGroup)
+	scim.Patch("/Groups/:id", scimHandler.PatchGroup)
+	scim.Delete("/Groups/:id", scimHandler.UnsupportedGroupOp)
+
+	authzService := services.NewAuthzService(permissionRepo, auditRepo, jwtCfg)
+	authzHandler := handler.NewAuthzHandler(authzService)
 
+	authz := app.Group("/authz",
+		security.Authenticate(jwtCfg.AccessSecret, apiKeyService.Verify, serviceAccountService.IsActive),
+		security.RequireService(),
+		security.RequireScope("authz:check"),
+		rateLimiter.Limit("authz_check", 600, time.Minute, func(ip, ua string) {
+			auditRepo.Log("AUTHZ_RATE_LIMIT", nil, ip, ua)
+		}),
+	)
+	authz.Post("/check", authzHandler.Check)
+	authz.Post("/check/batch", authzHandler.CheckBatch)
 }
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	DeletionModeDelete    = "delete"
+	DeletionModeAnonymize = "anonymize"
+
+	deletionBatchSize = 100
+	deletionLockKey   = "account_deletion:lock"
+)
+
+// AccountDeletionService handles self-service account deletion: a request
+// starts a grace period during which the user can cancel, after which
+// RunDeletionWorker deletes or anonymizes the account.
+type AccountDeletionService struct {
+	userRepo    *repositories.UserRepository
+	orgRepo     *repositories.OrganizationRepository
+	sessionRepo *repositories.SessionRepository
+	permRepo    *repositories.PermissionRepository
+	auditRepo   *repositories.AuditRepo
+	cfg         config.AccountConfig
+}
+
+func NewAccountDeletionService(userRepo *repositories.UserRepository, orgRepo *repositories.OrganizationRepository, sessionRepo *repositories.SessionRepository, permRepo *repositories.PermissionRepository, auditRepo *repositories.AuditRepo, cfg config.AccountConfig) *AccountDeletionService {
+	if cfg.DeletionMode != DeletionModeAnonymize {
+		cfg.DeletionMode = DeletionModeDelete
+	}
+
+	return &AccountDeletionService{
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		sessionRepo: sessionRepo,
+		permRepo:    permRepo,
+		auditRepo:   auditRepo,
+		cfg:         cfg,
+	}
+}
+
+// soleOwner reports whether deleting the user would leave an organization
+// that still has other members without an owner.
+func (s *AccountDeletionService) soleOwner(userID uint) (bool, error) {
+	orgs, err := s.orgRepo.ListForUser(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, org := range orgs {
+		if org.Role != models.OrgOwner {
+			continue
+		}
+
+		owners, err := s.orgRepo.CountOwners(org.OrganizationID)
+		if err != nil {
+			return false, err
+		}
+		if owners > 1 {
+			continue
+		}
+
+		members, err := s.orgRepo.ListMembers(org.OrganizationID)
+		if err != nil {
+			return false, err
+		}
+		if len(members) > 1 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RequestDeletion schedules the account for deletion after the grace period
+// and signs the user out everywhere. The password is required again so a
+// stolen session alone cannot delete the account.
+func (s *AccountDeletionService) RequestDeletion(userID uint, password, ip, ua string) (time.Time, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user == nil {
+		return time.Time{}, ErrNotFound
+	}
+
+	if !verifyPassword(user.Password, password) {
+		s.auditRepo.Log("ACCOUNT_DELETION_REAUTH_FAILED", &user.ID, ip, ua)
+		return time.Time{}, ErrInvalidCredentials
+	}
+
+	if user.Status != models.StatusActive {
+		return time.Time{}, ErrInvalidInput
+	}
+
+	soleOwner, err := s.soleOwner(user.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if soleOwner {
+		return time.Time{}, ErrLastOwner
+	}
+
+	scheduledFor := time.Now().Add(s.cfg.DeletionGrace)
+	scheduled, err := s.userRepo.ScheduleDeletion(user.ID, scheduledFor)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !scheduled {
+		return time.Time{}, ErrInvalidInput
+	}
+
+	ctx := context.Background()
+	_ = s.permRepo.InvalidateUser(ctx, user.ID)
+	if err := s.sessionRepo.DeleteAll(ctx, user.ID, ""); err != nil {
+		return time.Time{}, err
+	}
+
+	s.auditRepo.LogWithMetadata("ACCOUNT_DELETION_REQUESTED", &user.ID, ip, ua, map[string]any{
+		"scheduled_for": scheduledFor,
+		"mode":          s.cfg.DeletionMode,
+	})
+
+	return scheduledFor, nil
+}
+
+// CancelDeletion restores a pending account. Sessions were revoked by the
+// request, so the user proves ownership with email and password instead.
+func (s *AccountDeletionService) CancelDeletion(email, password, ip, ua string) error {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" || password == "" {
+		return ErrInvalidInput
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !verifyPassword(user.Password, password) {
+		s.auditRepo.Log("ACCOUNT_DELETION_CANCEL_FAILED", nil, ip, ua)
+		return ErrInvalidCredentials
+	}
+
+	cancelled, err := s.userRepo.CancelDeletion(user.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrNotFound
+	}
+	_ = s.permRepo.InvalidateUser(context.Background(), user.ID)
+
+	s.auditRepo.Log("ACCOUNT_DELETION_CANCELLED", &user.ID, ip, ua)
+
+	return nil
+}
+
+// PurgeDue deletes or anonymizes every account whose grace period is over
+// and returns how many were processed.
+func (s *AccountDeletionService) PurgeDue(now time.Time) (int, error) {
+	purged := 0
+
+	for {
+		users, err := s.userRepo.DueForDeletion(now, deletionBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		processed := 0
+		for _, user := range users {
+			var done bool
+			if s.cfg.DeletionMode == DeletionModeAnonymize {
+				email := fmt.Sprintf("deleted-%d@deleted.invalid", user.ID)
+				done, err = s.userRepo.AnonymizeDeleted(user.ID, email, unusablePassword, now)
+			} else {
+				done, err = s.userRepo.PurgeDeleted(user.ID, now)
+			}
+			if err != nil {
+				return purged, err
+			}
+			if !done {
+				// cancelled in the meantime
+				continue
+			}
+
+			processed++
+			_ = s.permRepo.InvalidateUser(context.Background(), user.ID)
+
+			// user_id is NULL after deletion, so the account is only named
+			// in metadata
+			s.auditRepo.LogWithMetadata("ACCOUNT_DELETED", nil, "", "", map[string]any{
+				"deleted_id": user.ID,
+				"mode":       s.cfg.DeletionMode,
+			})
+		}
+
+		purged += processed
+		if len(users) < deletionBatchSize || processed == 0 {
+			return purged, nil
+		}
+	}
+}
+
+// RunDeletionWorker calls PurgeDue every interval until ctx is done. A Redis
+// lock keeps concurrent instances from sweeping at the same time.
+func (s *AccountDeletionService) RunDeletionWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		locked, err := s.sessionRepo.Redis().SetNX(ctx, deletionLockKey, 1, interval).Result()
+		if err != nil || !locked {
+			continue
+		}
+
+		purged, err := s.PurgeDue(time.Now())
+		if err != nil {
+			log.Printf("account deletion sweep failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("account deletion sweep: %d accounts %sd", purged, s.cfg.DeletionMode)
+		}
+
+		s.sessionRepo.Redis().Del(ctx, deletionLockKey)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	apiKeyPrefix   = "ak"
+	maxAPIKeyTTL   = 365 * 24 * time.Hour
+	maxKeysPerUser = 25
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyScopes are the scopes an API key can be granted.
+var APIKeyScopes = []string{
+	"users:read",
+	"profile:read",
+	"profile:write",
+	"sessions:read",
+	"sessions:write",
+	"orgs:read",
+	"orgs:write",
+	"admin",
+	"authz:check",
+}
+
+type APIKeyService struct {
+	apiKeyRepo         *repositories.APIKeyRepository
+	userRepo           *repositories.UserRepository
+	orgRepo            *repositories.OrganizationRepository
+	serviceAccountRepo *repositories.ServiceAccountRepository
+	auditRepo          *repositories.AuditRepo
+}
+
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository, orgRepo *repositories.OrganizationRepository, serviceAccountRepo *repositories.ServiceAccountRepository, auditRepo *repositories.AuditRepo) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:         apiKeyRepo,
+		userRepo:           userRepo,
+		orgRepo:            orgRepo,
+		serviceAccountRepo: serviceAccountRepo,
+		auditRepo:          auditRepo,
+	}
+}
+
+// Create issues a new key for the user. The raw key is returned once and
+// only its hash is stored; the prefix is kept in clear for lookup.
+func (s *APIKeyService) Create(userID, orgID uint, name string, scopes []string, expiresIn time.Duration, ip, ua string) (*models.APIKey, string, error) {
+	existing, err := s.apiKeyRepo.ListByUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.APIKey{UserID: &userID}
+	if orgID != 0 {
+		key.OrganizationID = &orgID
+	}
+
+	rowKey, err := s.issue(key, existing, name, scopes, expiresIn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.auditRepo.LogWithMetadata("API_KEY_CREATED", &userID, ip, ua, map[string]any{
+		"key_id": key.ID,
+		"prefix": key.Prefix,
+		"scopes": key.Scopes,
+	})
+
+	return key, rowKey, nil
+}
+
+// CreateForServiceAccount issues a key owned by a service account instead of
+// a user. actorID is the admin performing the action.
+func (s *APIKeyService) CreateForServiceAccount(sa *models.ServiceAccount, actorID uint, name string, scopes []string, expiresIn time.Duration, ip, ua string) (*models.APIKey, string, error) {
+	existing, err := s.apiKeyRepo.ListByServiceAccount(sa.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.APIKey{
+		ServiceAccountID: &sa.ID,
+		OrganizationID:   sa.OrganizationID,
+	}
+
+	rowKey, err := s.issue(key, existing, name, scopes, expiresIn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.auditRepo.LogWithMetadata("SERVICE_ACCOUNT_KEY_CREATED", &actorID, ip, ua, map[string]any{
+		"service_account_id": sa.ID,
+		"key_id":             key.ID,
+		"prefix":             key.Prefix,
+		"scopes":             key.Scopes,
+	})
+
+	return key, rowKey, nil
+}
+
+func (s *APIKeyService) issue(key *models.APIKey, existing []models.APIKey, name string, scopes []string, expiresIn time.Duration) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || expiresIn < 0 || expiresIn > maxAPIKeyTTL {
+		return "", ErrInvalidInput
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return "", ErrInvalidInput
+		}
+	}
+
+	active := 0
+	for _, k := range existing {
+		if k.Active() {
+			active++
+		}
+	}
+	if active >= maxKeysPerUser {
+		return "", ErrForbidden
+	}
+
+	prefixByte := make([]byte, 6)
+	secretByte := make([]byte, 32)
+	if _, err := rand.Read(prefixByte); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secretByte); err != nil {
+		return "", err
+	}
+
+	key.Name = name
+	key.Prefix = hex.EncodeToString(prefixByte)
+	key.Scopes = strings.Join(scopes, " ")
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+
+	rowKey := apiKeyPrefix + "_" + key.Prefix + "_" + base64.RawURLEncoding.EncodeToString(secretByte)
+	if err := s.apiKeyRepo.Create(key, rowKey); err != nil {
+		return "", err
+	}
+
+	return rowKey, nil
+}
+
+func (s *APIKeyService) List(userID uint) ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListByUser(userID)
+}
+
+func (s *APIKeyService) Revoke(userID, keyID uint, ip, ua string) error {
+	revoked, err := s.apiKeyRepo.Revoke(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNotFound
+	}
+
+	s.auditRepo.LogWithMetadata("API_KEY_REVOKED", &userID, ip, ua, map[string]any{
+		"key_id": keyID,
+	})
+
+	return nil
+}
+
+// Verify implements security.APIKeyVerifier.
+func (s *APIKeyService) Verify(rowKey, ip string) (*security.Principal, error) {
+	parts := strings.SplitN(rowKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.FindByPrefix(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || !s.apiKeyRepo.MatchesHash(key, rowKey) {
+		s.auditRepo.Log("API_KEY_INVALID", nil, ip, "")
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.Active() {
+		if key.ServiceAccountID != nil {
+			s.auditRepo.LogServiceAccount("API_KEY_INVALID", *key.ServiceAccountID, ip, "", map[string]any{
+				"key_id": key.ID,
+			})
+		} else {
+			s.auditRepo.LogWithMetadata("API_KEY_INVALID", key.UserID, ip, "", map[string]any{
+				"key_id": key.ID,
+			})
+		}
+		return nil, ErrInvalidAPIKey
+	}
+
+	var principal *security.Principal
+	if key.ServiceAccountID != nil {
+		principal, err = s.serviceAccountPrincipal(key)
+	} else {
+		principal, err = s.userPrincipal(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.apiKeyRepo.TouchLastUsed(key.ID, ip)
+
+	return principal, nil
+}
+
+func (s *APIKeyService) userPrincipal(key *models.APIKey) (*security.Principal, error) {
+	if key.UserID == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.FindByID(*key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// keys stop working while the owner is suspended or has to reset their
+	// password, and work again once that is resolved
+	if user == nil || user.Status != models.StatusActive || user.MustResetPassword {
+		return nil, ErrInvalidAPIKey
+	}
+
+	principal := &security.Principal{
+		UserID:        user.ID,
+		Email:         user.Email,
+		Role:          string(user.Role),
+		Scopes:        key.ScopeList(),
+		KeyID:         key.ID,
+		PrincipalType: security.PrincipalUser,
+	}
+
+	// the key only acts in its organization while the owner is still a member
+	if key.OrganizationID != nil {
+		membership, err := s.orgRepo.ActiveMembership(*key.OrganizationID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if membership != nil {
+			principal.OrgID = membership.OrganizationID
+			principal.OrgRole = string(membership.Role)
+		}
+	}
+
+	return principal, nil
+}
+
+func (s *APIKeyService) serviceAccountPrincipal(key *models.APIKey) (*security.Principal, error) {
+	sa, err := s.serviceAccountRepo.FindByID(*key.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if sa == nil || sa.DisabledAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	principal := &security.Principal{
+		Role:             string(sa.Role),
+		Scopes:           key.ScopeList(),
+		KeyID:            key.ID,
+		PrincipalType:    security.PrincipalService,
+		ServiceAccountID: sa.ID,
+	}
+	if sa.OrganizationID != nil {
+		principal.OrgID = *sa.OrganizationID
+	}
+
+	return principal, nil
+}
@@ -6,8 +6,12 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
 	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
@@ -22,12 +26,29 @@ var (
 	ErrInvalidInput       = errors.New("invalid input")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user already exists")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrAccountSuspended   = errors.New("account suspended")
+	ErrMustResetPassword  = errors.New("password reset required")
+	ErrPendingDeletion    = errors.New("account scheduled for deletion")
+	ErrSessionExpired     = errors.New("session expired")
+	ErrSessionLimit       = errors.New("too many active sessions")
 )
 
 const (
-	maxLoginAttemts = 5
-	failWindow      = 10 * time.Minute
-	locakDuration   = 15 * time.Minute
+	maxLoginAttemts  = 5
+	failWindow       = 10 * time.Minute
+	locakDuration    = 15 * time.Minute
+	accountSetupTTL  = 72 * time.Hour
+	passwordResetTTL = 15 * time.Minute
+
+	SessionLimitEvictOldest = "evict_oldest"
+	SessionLimitReject      = "reject"
+
+	// authentication methods for the amr claim (RFC 8176)
+	AMRPassword = "pwd"
+	AMRSMS      = "sms"
+	AMRMFA      = "mfa"
 )
 
 type TokenPair struct {
@@ -35,6 +56,10 @@ type TokenPair struct {
 	RefreshToken string
 	ExpiresIn    int64
 	RefreshTTL   time.Duration
+	// TrustedDeviceToken is set when the user asked to remember the device
+	// while passing a second factor.
+	TrustedDeviceToken string
+	TrustedDeviceTTL   time.Duration
 }
 
 type AuthService struct {
@@ -43,20 +68,130 @@ type AuthService struct {
 	//tokenRepo *repositories.RefreshTokenRepository
 	auditRepo         *repositories.AuditRepo
 	sessionRepo       *repositories.SessionRepository
+	trustedDeviceRepo *repositories.TrustedDeviceRepository
 	passwordResetRepo *repositories.PasswordResetRepository
+	orgRepo           *repositories.OrganizationRepository
+	sessionCfg        config.SessionConfig
+	mailer            Mailer
+	// frontendURL hosts the page password reset and setup links open.
+	frontendURL string
+	// mfa, when set, adds a second-factor step to Login for users who
+	// enabled one.
+	mfa *MFAService
+	// alerts, when set, emails users about logins from new devices or
+	// countries.
+	alerts *LoginAlertService
+	// risk, when set, scores each login and can require a second factor
+	// or refuse it.
+	risk *RiskService
 }
 
-func NewAuthService(repo *repositories.UserRepository, jwtCfg config.JWTConfig, sessionRepo *repositories.SessionRepository, auditRepo *repositories.AuditRepo, passwordResetRepo *repositories.PasswordResetRepository) *AuthService {
+func NewAuthService(repo *repositories.UserRepository, jwtCfg config.JWTConfig, sessionCfg config.SessionConfig, sessionRepo *repositories.SessionRepository, trustedDeviceRepo *repositories.TrustedDeviceRepository, auditRepo *repositories.AuditRepo, passwordResetRepo *repositories.PasswordResetRepository, orgRepo *repositories.OrganizationRepository, mailer Mailer, frontendURL string) *AuthService {
 	return &AuthService{
 		userRepo:          repo,
 		jwtCfg:            jwtCfg,
 		sessionRepo:       sessionRepo,
+		trustedDeviceRepo: trustedDeviceRepo,
 		auditRepo:         auditRepo,
 		passwordResetRepo: passwordResetRepo,
+		orgRepo:           orgRepo,
+		sessionCfg:        sessionCfg,
+		mailer:            mailer,
+		frontendURL:       strings.TrimRight(frontendURL, "/"),
 	}
 }
 
-func (s *AuthService) Register(email string, password string, role string) error {
+// SetMFA adds the SMS second factor to Login.
+func (s *AuthService) SetMFA(mfa *MFAService) {
+	s.mfa = mfa
+}
+
+// SetLoginAlerts emails users about logins from new devices or countries.
+func (s *AuthService) SetLoginAlerts(alerts *LoginAlertService) {
+	s.alerts = alerts
+}
+
+// SetRisk scores each login before it is let through.
+func (s *AuthService) SetRisk(risk *RiskService) {
+	s.risk = risk
+}
+
+// newSessionLifetime sets the limits for a session started now.
+func (s *AuthService) newSessionLifetime(now time.Time) repositories.SessionLifetime {
+	lifetime := repositories.SessionLifetime{
+		StartedAt:   now,
+		IdleTimeout: s.sessionCfg.IdleTimeout,
+	}
+	if s.sessionCfg.MaxLifetime > 0 {
+		lifetime.ExpiresAt = now.Add(s.sessionCfg.MaxLifetime)
+	}
+	return lifetime
+}
+
+// sessionTTL is how long a session created now may live: the refresh TTL,
+// shortened by the idle timeout and the absolute expiry.
+func (s *AuthService) sessionTTL(lifetime repositories.SessionLifetime, now time.Time) time.Duration {
+	ttl := s.jwtCfg.RefreshTTL
+	if lifetime.IdleTimeout > 0 && lifetime.IdleTimeout < ttl {
+		ttl = lifetime.IdleTimeout
+	}
+	if !lifetime.ExpiresAt.IsZero() {
+		if remaining := lifetime.ExpiresAt.Sub(now); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+// issueAccessToken signs an access token for the user with the given
+// membership as the active organization (nil for no organization). The
+// auth_time and amr claims come from the session's lifetime.
+func (s *AuthService) issueAccessToken(user *models.UserModel, membership *models.Membership, lifetime repositories.SessionLifetime) (string, error) {
+	claims := s.accessClaims(user, membership)
+	claims.AuthTime = unixOrZero(lifetime.AuthTime)
+	claims.AMR = lifetime.AMR
+	return security.SignAccessToken(claims, s.jwtCfg.AccessSecret, s.jwtCfg.AccessTTL)
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func (s *AuthService) accessClaims(user *models.UserModel, membership *models.Membership) security.AccessClaims {
+	claims := security.AccessClaims{
+		UserID:        user.ID,
+		Email:         user.Email,
+		Role:          string(user.Role),
+		PrincipalType: security.PrincipalUser,
+	}
+
+	if membership != nil {
+		claims.OrgID = membership.OrganizationID
+		claims.OrgRole = string(membership.Role)
+	}
+
+	for _, claim := range s.jwtCfg.ProfileClaims {
+		switch claim {
+		case "name":
+			claims.Name = user.DisplayName
+		case "locale":
+			claims.Locale = user.Locale
+		case "zoneinfo":
+			claims.Zoneinfo = user.Timezone
+		case "picture":
+			claims.Picture = user.AvatarURL
+		}
+	}
+
+	return claims
+}
+
+// Register creates a regular user. Admins are only made through
+// AdminService.UpdateRole, never by signing up.
+func (s *AuthService) Register(email string, password string) error {
 	email = strings.TrimSpace(strings.ToLower(email))
 
 	if email == "" || password == "" {
@@ -82,39 +217,49 @@ func (s *AuthService) Register(email string, password string, role string) error
 	user := &models.UserModel{
 		Email:    email,
 		Password: string(hash),
-		Role:     models.UserRole(role),
+		Role:     models.User,
 	}
 
 	return s.userRepo.Create(user)
 }
 
-func (s *AuthService) Login(email, password, ip, ua string) (*TokenPair, error) {
-	email = strings.TrimSpace(strings.ToLower(email))
+// Login authenticates with an email address, username or E.164 phone number.
+// trustedDevice is the remembered-device token, if the client has one; it
+// lets the login skip the second factor.
+func (s *AuthService) Login(identifier, password, trustedDevice, ip, ua string) (*TokenPair, error) {
+	identifier = strings.TrimSpace(identifier)
 
-	if email == "" || password == "" {
+	if identifier == "" || password == "" {
 		return nil, ErrInvalidInput
 	}
 
 	ctx := context.Background()
 
-	if s.IsLocking(ctx, email) {
-		s.auditRepo.Log("ACCOUNT_LOCKED", nil, ip, ua)
-		return nil, errors.New("account temporarily locked")
-	}
-
-	user, err := s.userRepo.FindByEmail(email)
+	user, err := s.findByIdentifier(identifier)
 	if err != nil {
 		s.auditRepo.Log("LOGIN_FAILED", nil, ip, ua)
 		return nil, err
 	}
 
+	// lock per account rather than per identifier, so switching between
+	// email, username and phone does not reset the attempt counter
+	lockKey := strings.ToLower(identifier)
+	if user != nil {
+		lockKey = user.Email
+	}
+
+	if s.IsLocking(ctx, lockKey) {
+		s.auditRepo.Log("ACCOUNT_LOCKED", nil, ip, ua)
+		return nil, errors.New("account temporarily locked")
+	}
+
 	if user == nil {
 		s.auditRepo.Log("LOGIN_FAILED", nil, ip, ua)
 		return nil, ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		_ = s.RecordFailedLogin(ctx, email)
+	if !verifyPassword(user.Password, password) {
+		_ = s.RecordFailedLogin(ctx, lockKey)
 		s.auditRepo.Log(
 			"LOGIN_FAILED",
 			nil,
@@ -124,7 +269,140 @@ func (s *AuthService) Login(email, password, ip, ua string) (*TokenPair, error)
 		return nil, ErrInvalidCredentials
 	}
 
-	s.ClearFailLogin(ctx, email)
+	failedAttempts := s.FailedLoginCount(ctx, lockKey)
+	s.ClearFailLogin(ctx, lockKey)
+
+	if err := s.checkLoginAllowed(user, ip, ua); err != nil {
+		return nil, err
+	}
+
+	stepUp := false
+	if s.risk != nil {
+		assessment := s.risk.Assess(user, ip, ua, failedAttempts)
+		// a risky login from an account with no verified phone cannot be
+		// stepped up; it is blocked rather than let through unchallenged
+		if assessment.Decision == RiskStepUp && !s.canStepUp(user) {
+			assessment.Decision = RiskBlock
+			assessment.Details["step_up_unavailable"] = true
+		}
+		s.risk.Log(user, ip, ua, assessment)
+
+		switch assessment.Decision {
+		case RiskBlock:
+			return nil, ErrLoginBlocked
+		case RiskStepUp:
+			stepUp = true
+		}
+	}
+
+	if (user.MFASMSEnabled || stepUp) && s.mfa != nil {
+		if trustedDeviceMaySkip(user, stepUp) {
+			if deviceID := s.trustedDeviceID(ctx, user, trustedDevice, ip, ua); deviceID != "" {
+				s.auditRepo.LogWithMetadata("MFA_SKIPPED_TRUSTED_DEVICE", &user.ID, ip, ua, map[string]any{
+					"device_id": deviceID,
+				})
+				return s.issueSession(user, ip, ua, []string{AMRPassword})
+			}
+		}
+
+		challenge, err := s.mfa.StartChallenge(user, ip, ua)
+		if err != nil {
+			return nil, err
+		}
+		return nil, challenge
+	}
+
+	return s.issueSession(user, ip, ua, []string{AMRPassword})
+}
+
+// trustedDeviceMaySkip reports whether a remembered device may skip the
+// second factor. It only stands in for the user's own SMS two-factor; a
+// risk step-up is always challenged, since a stolen cookie replayed from
+// a new location is exactly what the risk engine is there to catch.
+func trustedDeviceMaySkip(user *models.UserModel, stepUp bool) bool {
+	return user.MFASMSEnabled && !stepUp
+}
+
+// canStepUp reports whether the user can be asked for an SMS code.
+func (s *AuthService) canStepUp(user *models.UserModel) bool {
+	return s.mfa != nil && user.Phone != nil && user.PhoneVerifiedAt != nil
+}
+
+// checkLoginAllowed rejects accounts that passed authentication but may not
+// sign in in their current state.
+func (s *AuthService) checkLoginAllowed(user *models.UserModel, ip, ua string) error {
+	if user.Status == models.StatusSuspended {
+		s.auditRepo.Log("LOGIN_BLOCKED_SUSPENDED", &user.ID, ip, ua)
+		return ErrAccountSuspended
+	}
+
+	if user.Status == models.StatusPendingDeletion {
+		s.auditRepo.Log("LOGIN_BLOCKED_PENDING_DELETION", &user.ID, ip, ua)
+		return ErrPendingDeletion
+	}
+
+	if user.MustResetPassword {
+		s.auditRepo.Log("LOGIN_BLOCKED_RESET_REQUIRED", &user.ID, ip, ua)
+		return ErrMustResetPassword
+	}
+
+	return nil
+}
+
+// enforceSessionLimit makes room for one more session under the user's cap,
+// either by ending the oldest sessions or by refusing the login.
+func (s *AuthService) enforceSessionLimit(ctx context.Context, user *models.UserModel, ip, ua string) error {
+	limit := s.sessionCfg.MaxPerUser
+	if user.Role == models.Admin {
+		limit = s.sessionCfg.MaxPerAdmin
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	sessions, err := s.sessionRepo.ListByUsers(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) < limit {
+		return nil
+	}
+
+	if s.sessionCfg.LimitPolicy == SessionLimitReject {
+		s.auditRepo.LogWithMetadata("SESSION_LIMIT_REJECTED", &user.ID, ip, ua, map[string]any{
+			"limit": limit,
+		})
+		return ErrSessionLimit
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].StartedAt != sessions[j].StartedAt {
+			return sessions[i].StartedAt < sessions[j].StartedAt
+		}
+		return sessions[i].CreatedAt < sessions[j].CreatedAt
+	})
+
+	for _, old := range sessions[:len(sessions)-limit+1] {
+		if err := s.sessionRepo.Delete(ctx, old.SessionID, user.ID); err != nil {
+			return err
+		}
+		s.auditRepo.LogWithMetadata("SESSION_EVICTED", &user.ID, ip, ua, map[string]any{
+			"session_id": old.SessionID,
+			"reason":     "session_limit",
+			"limit":      limit,
+		})
+	}
+
+	return nil
+}
+
+// issueSession completes a login: it creates the session and signs the
+// access and refresh tokens. amr lists how the user authenticated.
+func (s *AuthService) issueSession(user *models.UserModel, ip, ua string, amr []string) (*TokenPair, error) {
+	if err := s.enforceSessionLimit(context.Background(), user, ip, ua); err != nil {
+		return nil, err
+	}
+
 	s.auditRepo.Log(
 		"LOGIN_SUCCESS",
 		&user.ID,
@@ -132,25 +410,30 @@ func (s *AuthService) Login(email, password, ip, ua string) (*TokenPair, error)
 		ua,
 	)
 
-	accessToken, err := security.GenerateAccessToken(
-		user.ID,
-		user.Email,
-		string(user.Role),
-		s.jwtCfg.AccessSecret,
-		s.jwtCfg.AccessTTL,
-	)
-
+	membership, err := s.orgRepo.DefaultMembership(user.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	sessionID := uuid.NewString()
+	now := time.Now()
+	lifetime := s.newSessionLifetime(now)
+	lifetime.AuthTime = now
+	lifetime.AMR = amr
+	lifetime.Binding = s.sessionBinding(ip, ua)
+	ttl := s.sessionTTL(lifetime, now)
+
+	accessToken, err := s.issueAccessToken(user, membership, lifetime)
+
+	if err != nil {
+		return nil, err
+	}
 
 	refreshToken, err := security.GenerateRefreshToken(
 		user.ID,
 		sessionID,
 		s.jwtCfg.RefreshSecret,
-		s.jwtCfg.RefreshTTL,
+		ttl,
 	)
 
 	if err != nil {
@@ -166,25 +449,66 @@ func (s *AuthService) Login(email, password, ip, ua string) (*TokenPair, error)
 		user.ID,
 		ip,
 		ua,
-		s.jwtCfg.RefreshTTL,
+		ttl,
+		lifetime,
 	); err != nil {
 		return nil, err
 	}
 
+	if membership != nil {
+		_ = s.sessionRepo.SetActiveOrg(ctx, sessionID, membership.OrganizationID)
+	}
+
+	if s.alerts != nil {
+		go s.alerts.CheckLogin(user, sessionID, ip, ua)
+	}
+	if s.risk != nil {
+		go s.risk.RecordLogin(user.ID, ip)
+	}
+
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresIn:    int64(s.jwtCfg.AccessTTL.Seconds()),
-		RefreshTTL:   s.jwtCfg.RefreshTTL,
+		RefreshTTL:   ttl,
 	}, nil
 }
 
-func (s *AuthService) GetAllUsers() ([]models.UserModel, error) {
-	return s.userRepo.GetAllUsers()
+// ListOrgUsers lists the users of orgID. The caller's membership is looked
+// up again rather than trusted from the access token, which may predate a
+// removal or suspension. Service accounts (callerID 0) are bound to their
+// own organization.
+func (s *AuthService) ListOrgUsers(callerID, orgID uint, filter repositories.UserFilter) (*repositories.UserPage, error) {
+	if orgID == 0 {
+		return nil, ErrForbidden
+	}
+
+	if callerID != 0 {
+		membership, err := s.orgRepo.ActiveMembership(orgID, callerID)
+		if err != nil {
+			return nil, err
+		}
+		if membership == nil {
+			return nil, ErrForbidden
+		}
+	}
+
+	filter.OrgID = orgID
+	return s.ListUsers(filter)
 }
 
-func (s *AuthService) GetAllAdmins() ([]models.UserModel, error) {
-	return s.userRepo.GetAllAdmins()
+// ListUsers lists users across organizations unless filter.OrgID is set.
+func (s *AuthService) ListUsers(filter repositories.UserFilter) (*repositories.UserPage, error) {
+	page, err := s.userRepo.ListUsers(filter)
+	if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, repositories.ErrInvalidSort) {
+		return nil, ErrInvalidInput
+	}
+	return page, err
+}
+
+func (s *AuthService) ListAdmins(filter repositories.UserFilter) (*repositories.UserPage, error) {
+	filter.Role = string(models.Admin)
+	return s.ListUsers(filter)
 }
 
 func (s *AuthService) Refresh(refreshToken string, ip, ua string) (*TokenPair, error) {
@@ -204,16 +528,69 @@ func (s *AuthService) Refresh(refreshToken string, ip, ua string) (*TokenPair, e
 		return nil, ErrInvalidCredentials
 	}
 
+	session, err := s.sessionRepo.Get(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	activeOrgID, _ := s.sessionRepo.GetActiveOrg(ctx, claims.SessionID)
+
 	// rotate session
 	_ = s.sessionRepo.Delete(ctx, claims.SessionID, userID)
 
+	now := time.Now()
+	if session.ExpiresAt != 0 && now.Unix() >= session.ExpiresAt {
+		s.auditRepo.Log("SESSION_EXPIRED_ABSOLUTE", &userID, ip, ua)
+		return nil, ErrSessionExpired
+	}
+	if session.IdleExpiresAt != 0 && now.Unix() >= session.IdleExpiresAt {
+		s.auditRepo.Log("SESSION_EXPIRED_IDLE", &userID, ip, ua)
+		return nil, ErrSessionExpired
+	}
+
+	binding := s.sessionBinding(ip, ua)
+	if err := s.checkSessionBinding(userID, session, binding, ip, ua); err != nil {
+		return nil, err
+	}
+
+	// the new session keeps the original login time and absolute expiry; it
+	// is bound to the client now holding it, so a flagged change is only
+	// reported once
+	lifetime := session.Lifetime(s.sessionCfg.IdleTimeout)
+	lifetime.Binding = binding
+	ttl := s.sessionTTL(lifetime, now)
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Status != models.StatusActive || user.MustResetPassword {
+		s.auditRepo.Log("REFRESH_BLOCKED", &user.ID, ip, ua)
+		return nil, ErrInvalidCredentials
+	}
+
+	var membership *models.Membership
+	if activeOrgID != 0 {
+		membership, err = s.orgRepo.ActiveMembership(activeOrgID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	newSessionID := uuid.NewString()
 
 	newRefreshToken, err := security.GenerateRefreshToken(
 		userID,
 		newSessionID,
 		s.jwtCfg.RefreshSecret,
-		s.jwtCfg.RefreshTTL,
+		ttl,
 	)
 	if err != nil {
 		return nil, err
@@ -225,15 +602,15 @@ func (s *AuthService) Refresh(refreshToken string, ip, ua string) (*TokenPair, e
 		userID,
 		ip,
 		ua,
-		s.jwtCfg.RefreshTTL,
+		ttl,
+		lifetime,
 	)
 
-	accessToken, err := security.GenerateAccessToken(
-		userID,
-		"", "",
-		s.jwtCfg.AccessSecret,
-		s.jwtCfg.AccessTTL,
-	)
+	if membership != nil {
+		_ = s.sessionRepo.SetActiveOrg(ctx, newSessionID, membership.OrganizationID)
+	}
+
+	accessToken, err := s.issueAccessToken(user, membership, lifetime)
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +619,7 @@ func (s *AuthService) Refresh(refreshToken string, ip, ua string) (*TokenPair, e
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		ExpiresIn:    int64(s.jwtCfg.AccessTTL.Seconds()),
-		RefreshTTL:   s.jwtCfg.RefreshTTL,
+		RefreshTTL:   ttl,
 	}, nil
 }
 
@@ -267,8 +644,88 @@ func (s *AuthService) Logout(refreshToken, ip, ua string) error {
 	)
 }
 
-func (s *AuthService) ListSessions(userID uint) ([]repositories.SessionInfo, error) {
-	return s.sessionRepo.ListByUsers(context.Background(), userID)
+// Session list orderings accepted by ListSessions.
+const (
+	SessionSortLastActive = "last_active_at"
+	SessionSortStarted    = "started_at"
+	SessionSortCreated    = "created_at"
+
+	maxSessionNameLen = 64
+)
+
+// currentSessionID returns the session behind the caller's refresh token,
+// or "" if it is missing or belongs to someone else.
+func (s *AuthService) currentSessionID(userID uint, refreshToken string) string {
+	if refreshToken == "" {
+		return ""
+	}
+
+	claims, err := security.ParseRefreshToken(refreshToken, s.jwtCfg.RefreshSecret)
+	if err != nil || claims.UserID != userID {
+		return ""
+	}
+	return claims.SessionID
+}
+
+// ListSessions returns the user's sessions sorted by sortBy (most recent
+// first unless asc), flagging the one the refresh token belongs to.
+func (s *AuthService) ListSessions(userID uint, refreshToken, sortBy string, asc bool) ([]repositories.SessionInfo, error) {
+	var key func(repositories.SessionInfo) int64
+	switch sortBy {
+	case "", SessionSortLastActive:
+		key = func(info repositories.SessionInfo) int64 { return info.LastActiveAt }
+	case SessionSortStarted:
+		key = func(info repositories.SessionInfo) int64 { return info.StartedAt }
+	case SessionSortCreated:
+		key = func(info repositories.SessionInfo) int64 { return info.CreatedAt }
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	sessions, err := s.sessionRepo.ListByUsers(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := s.currentSessionID(userID, refreshToken)
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].SessionID == current
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if asc {
+			return key(sessions[i]) < key(sessions[j])
+		}
+		return key(sessions[i]) > key(sessions[j])
+	})
+
+	return sessions, nil
+}
+
+// RenameSession sets the user's name for one of their sessions; an empty
+// name clears it.
+func (s *AuthService) RenameSession(userID uint, sessionID, name, ip, ua string) error {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxSessionNameLen {
+		return ErrInvalidInput
+	}
+
+	ctx := context.Background()
+
+	storedUserID, err := s.sessionRepo.GetUserID(ctx, sessionID)
+	if err != nil || storedUserID != userID {
+		return ErrNotFound
+	}
+
+	if err := s.sessionRepo.SetName(ctx, sessionID, name); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("SESSION_RENAMED", &userID, ip, ua, map[string]any{
+		"session_id": sessionID,
+	})
+
+	return nil
 }
 
 func (s *AuthService) IsLocking(ctx context.Context, email string) bool {
@@ -277,6 +734,13 @@ func (s *AuthService) IsLocking(ctx context.Context, email string) bool {
 	return exists == 1
 }
 
+// FailedLoginCount is the number of wrong passwords in the current
+// failure window.
+func (s *AuthService) FailedLoginCount(ctx context.Context, email string) int {
+	count, _ := s.sessionRepo.Redis().Get(ctx, "login_fail:"+email).Int()
+	return count
+}
+
 func (s *AuthService) RecordFailedLogin(ctx context.Context, email string) error {
 	failKey := "login_fail:" + email
 	lockKey := "login_lock:" + email
@@ -327,17 +791,7 @@ func (s *AuthService) LogoutSession(userID uint, sessionID string, ip, ua string
 }
 
 func (s *AuthService) LogoutAllSessions(userID uint, refreshToken, ip, ua string) error {
-	currentSessionID := ""
-
-	if refreshToken != "" {
-		claims, err := security.ParseRefreshToken(
-			refreshToken,
-			s.jwtCfg.RefreshSecret,
-		)
-		if err == nil && claims.UserID == userID {
-			currentSessionID = claims.SessionID
-		}
-	}
+	currentSessionID := s.currentSessionID(userID, refreshToken)
 
 	err := s.sessionRepo.DeleteAll(
 		context.Background(),
@@ -349,6 +803,10 @@ func (s *AuthService) LogoutAllSessions(userID uint, refreshToken, ip, ua string
 		return err
 	}
 
+	if err := s.trustedDeviceRepo.DeleteAll(context.Background(), userID); err != nil {
+		return err
+	}
+
 	s.auditRepo.Log(
 		"LOGOUT_ALL",
 		&userID,
@@ -376,11 +834,15 @@ func (s *AuthService) RequestPasswordReset(email, ip, ua string) error {
 		ctx,
 		rowToken,
 		user.ID,
-		15*time.Minute,
+		passwordResetTTL,
 	)
 
-	// TODO: send email (for now just log)
-	fmt.Println("RESET_TOKEN:", rowToken)
+	body := "Someone asked to reset the password of your account. If it was you, open this link to choose a new one:\n"
+	if err := s.sendPasswordLink(user, "Reset your password", body, rowToken, passwordResetTTL); err != nil {
+		// the caller never learns whether the email exists, so this is
+		// only logged
+		log.Printf("password reset email for user %d failed: %v", user.ID, err)
+	}
 	s.auditRepo.Log(
 		"PWD_RESET_REQUEST",
 		&user.ID,
@@ -391,6 +853,51 @@ func (s *AuthService) RequestPasswordReset(email, ip, ua string) error {
 	return nil
 }
 
+// SendAccountSetup issues a password reset token for an account created
+// without a password, e.g. by a bulk import. It lives longer than a normal
+// reset token since the user did not ask for it.
+func (s *AuthService) SendAccountSetup(user *models.UserModel, ip, ua string) error {
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		return err
+	}
+	rowToken := base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	if err := s.passwordResetRepo.Store(context.Background(), rowToken, user.ID, accountSetupTTL); err != nil {
+		return err
+	}
+
+	body := "An account was created for you. Open this link to choose your password:\n"
+	if err := s.sendPasswordLink(user, "Set up your account", body, rowToken, accountSetupTTL); err != nil {
+		log.Printf("account setup email for user %d failed: %v", user.ID, err)
+		_ = s.passwordResetRepo.Delete(context.Background(), rowToken)
+		return err
+	}
+	s.auditRepo.Log("ACCOUNT_SETUP_SENT", &user.ID, ip, ua)
+
+	return nil
+}
+
+// sendPasswordLink emails the user a link to the page that redeems a
+// password reset token.
+func (s *AuthService) sendPasswordLink(user *models.UserModel, subject, intro, rowToken string, ttl time.Duration) error {
+	link := s.frontendURL + "/reset-password?token=" + url.QueryEscape(rowToken)
+
+	var b strings.Builder
+	b.WriteString(intro)
+	fmt.Fprintf(&b, "%s\n\n", link)
+	if ttl >= 24*time.Hour {
+		fmt.Fprintf(&b, "The link works once and expires in %d days.\n", int(ttl.Hours()/24))
+	} else {
+		fmt.Fprintf(&b, "The link works once and expires in %d minutes.\n", int(ttl.Minutes()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return s.mailer.Send(ctx, user.Email, subject, b.String())
+}
+
 func (s *AuthService) ResetPassword(token, newPassword, ip, ua string) error {
 	ctx := context.Background()
 	userID, err := s.passwordResetRepo.Get(ctx, token)
@@ -415,6 +922,7 @@ func (s *AuthService) ResetPassword(token, newPassword, ip, ua string) error {
 
 	_ = s.passwordResetRepo.Delete(ctx, token)
 	_ = s.sessionRepo.DeleteAll(ctx, uint(userID), "")
+	_ = s.trustedDeviceRepo.DeleteAll(ctx, userID)
 
 	s.auditRepo.Log(
 		"PWD_REST_PASSWORD",
@@ -425,3 +933,115 @@ func (s *AuthService) ResetPassword(token, newPassword, ip, ua string) error {
 
 	return nil
 }
+
+// Reauthenticate checks the user's password again and mints an access
+// token with a fresh auth_time, for routes behind RequireRecentAuth. The
+// caller's session is updated too, so tokens from later refreshes keep it.
+func (s *AuthService) Reauthenticate(userID uint, password, refreshToken, ip, ua string) (*TokenPair, error) {
+	if password == "" {
+		return nil, ErrInvalidInput
+	}
+
+	ctx := context.Background()
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// wrong passwords count towards the same lockout as Login
+	if s.IsLocking(ctx, user.Email) {
+		s.auditRepo.Log("REAUTH_LOCKED", &user.ID, ip, ua)
+		return nil, ErrInvalidCredentials
+	}
+	if !verifyPassword(user.Password, password) {
+		_ = s.RecordFailedLogin(ctx, user.Email)
+		s.auditRepo.Log("REAUTH_FAILED", &user.ID, ip, ua)
+		return nil, ErrInvalidCredentials
+	}
+	s.ClearFailLogin(ctx, user.Email)
+
+	if err := s.checkLoginAllowed(user, ip, ua); err != nil {
+		return nil, err
+	}
+
+	lifetime := repositories.SessionLifetime{
+		AuthTime: time.Now(),
+		AMR:      []string{AMRPassword},
+	}
+
+	var membership *models.Membership
+	if sessionID := s.currentSessionID(userID, refreshToken); sessionID != "" {
+		if err := s.sessionRepo.SetAuth(ctx, sessionID, lifetime.AuthTime, lifetime.AMR); err != nil {
+			return nil, err
+		}
+		if orgID, _ := s.sessionRepo.GetActiveOrg(ctx, sessionID); orgID != 0 {
+			membership, err = s.orgRepo.ActiveMembership(orgID, userID)
+		}
+	} else {
+		membership, err = s.orgRepo.DefaultMembership(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.issueAccessToken(user, membership, lifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditRepo.Log("REAUTHENTICATED", &user.ID, ip, ua)
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.jwtCfg.AccessTTL.Seconds()),
+	}, nil
+}
+
+// SwitchOrganization makes orgID the active organization of the caller's
+// session and mints a new access token carrying it.
+func (s *AuthService) SwitchOrganization(userID, orgID uint, refreshToken, ip, ua string) (*TokenPair, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	membership, err := s.orgRepo.ActiveMembership(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, ErrForbidden
+	}
+
+	// keep the switch across refreshes when the caller's session is known;
+	// the session also carries auth_time over to the new token
+	var lifetime repositories.SessionLifetime
+	if sessionID := s.currentSessionID(userID, refreshToken); sessionID != "" {
+		ctx := context.Background()
+		_ = s.sessionRepo.SetActiveOrg(ctx, sessionID, orgID)
+		if session, err := s.sessionRepo.Get(ctx, sessionID); err == nil && session != nil {
+			lifetime = session.Lifetime(s.sessionCfg.IdleTimeout)
+		}
+	}
+
+	accessToken, err := s.issueAccessToken(user, membership, lifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_SWITCH", &userID, ip, ua, map[string]any{
+		"org_id": orgID,
+	})
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.jwtCfg.AccessTTL.Seconds()),
+	}, nil
+}
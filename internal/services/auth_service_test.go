@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+)
+
+func TestTrustedDeviceMaySkip(t *testing.T) {
+	tests := []struct {
+		name       string
+		mfaEnabled bool
+		stepUp     bool
+		want       bool
+	}{
+		{name: "sms two-factor", mfaEnabled: true, stepUp: false, want: true},
+		{name: "sms two-factor and risk step-up", mfaEnabled: true, stepUp: true, want: false},
+		{name: "risk step-up only", mfaEnabled: false, stepUp: true, want: false},
+		{name: "no second factor", mfaEnabled: false, stepUp: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.UserModel{MFASMSEnabled: tt.mfaEnabled}
+			if got := trustedDeviceMaySkip(user, tt.stepUp); got != tt.want {
+				t.Errorf("trustedDeviceMaySkip(mfa=%v, stepUp=%v) = %v, want %v", tt.mfaEnabled, tt.stepUp, got, tt.want)
+			}
+		})
+	}
+}
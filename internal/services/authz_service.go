@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const maxBatchChecks = 100
+
+type AuthzCheck struct {
+	Token    string `json:"token"`
+	Subject  uint   `json:"subject"`
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+}
+
+type AuthzDecision struct {
+	Allowed          bool   `json:"allowed"`
+	Reason           string `json:"reason"`
+	Subject          uint   `json:"subject,omitempty"`
+	ServiceAccountID uint   `json:"service_account_id,omitempty"`
+	Role             string `json:"role,omitempty"`
+	Action           string `json:"action"`
+	Resource         string `json:"resource"`
+}
+
+// AuthzService answers "can subject X perform action Y on resource Z" for
+// other microservices so they don't have to re-implement role checks.
+type AuthzService struct {
+	permRepo  *repositories.PermissionRepository
+	auditRepo *repositories.AuditRepo
+	jwtCfg    config.JWTConfig
+}
+
+func NewAuthzService(permRepo *repositories.PermissionRepository, auditRepo *repositories.AuditRepo, jwtCfg config.JWTConfig) *AuthzService {
+	return &AuthzService{
+		permRepo:  permRepo,
+		auditRepo: auditRepo,
+		jwtCfg:    jwtCfg,
+	}
+}
+
+func (s *AuthzService) Check(check AuthzCheck, ip, ua string) (*AuthzDecision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	decision, err := s.decide(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if !decision.Allowed {
+		metadata := map[string]any{
+			"action":   decision.Action,
+			"resource": decision.Resource,
+			"reason":   decision.Reason,
+		}
+
+		if decision.ServiceAccountID != 0 {
+			s.auditRepo.LogServiceAccount("AUTHZ_DENIED", decision.ServiceAccountID, ip, ua, metadata)
+		} else {
+			var userID *uint
+			if decision.Subject != 0 {
+				userID = &decision.Subject
+			}
+			s.auditRepo.LogWithMetadata("AUTHZ_DENIED", userID, ip, ua, metadata)
+		}
+	}
+
+	return decision, nil
+}
+
+func (s *AuthzService) CheckBatch(checks []AuthzCheck, ip, ua string) ([]AuthzDecision, error) {
+	if len(checks) == 0 || len(checks) > maxBatchChecks {
+		return nil, ErrInvalidInput
+	}
+
+	decisions := make([]AuthzDecision, 0, len(checks))
+	for _, check := range checks {
+		decision, err := s.Check(check, ip, ua)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, *decision)
+	}
+
+	return decisions, nil
+}
+
+func (s *AuthzService) decide(ctx context.Context, check AuthzCheck) (*AuthzDecision, error) {
+	decision := &AuthzDecision{
+		Subject:  check.Subject,
+		Action:   strings.TrimSpace(check.Action),
+		Resource: strings.TrimSpace(check.Resource),
+	}
+
+	if decision.Action == "" || decision.Resource == "" {
+		decision.Reason = "action and resource are required"
+		return decision, nil
+	}
+
+	if check.Token != "" {
+		claims, err := security.ParseAccessToken(check.Token, s.jwtCfg.AccessSecret)
+		if err != nil || claims == nil {
+			decision.Reason = "invalid or expired token"
+			return decision, nil
+		}
+		decision.Subject = claims.UserID
+		if claims.PrincipalType == security.PrincipalService {
+			decision.ServiceAccountID = claims.ServiceAccountID
+		}
+	}
+
+	if decision.Subject == 0 && decision.ServiceAccountID == 0 {
+		decision.Reason = "token or subject is required"
+		return decision, nil
+	}
+
+	var role string
+	var err error
+	if decision.ServiceAccountID != 0 {
+		role, err = s.permRepo.GetServiceAccountRole(ctx, decision.ServiceAccountID)
+	} else {
+		role, err = s.permRepo.GetUserRole(ctx, decision.Subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		decision.Reason = "subject not found or not active"
+		return decision, nil
+	}
+	decision.Role = role
+
+	perms, err := s.permRepo.GetRolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, perm := range perms {
+		if matchPermission(perm, decision.Action, decision.Resource) {
+			decision.Allowed = true
+			decision.Reason = "granted by role " + role + " (" + perm.Action + " on " + perm.Resource + ")"
+			return decision, nil
+		}
+	}
+
+	decision.Reason = "no permission for role " + role
+	return decision, nil
+}
+
+// matchPermission supports "*" for any action/resource and a trailing "*"
+// for resource prefixes, e.g. "invoices:*" matches "invoices:42".
+func matchPermission(perm models.RolePermission, action, resource string) bool {
+	if perm.Action != "*" && perm.Action != action {
+		return false
+	}
+
+	if perm.Resource == "*" || perm.Resource == resource {
+		return true
+	}
+
+	if prefix, ok := strings.CutSuffix(perm.Resource, "*"); ok {
+		return strings.HasPrefix(resource, prefix)
+	}
+
+	return false
+}
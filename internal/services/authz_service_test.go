@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+)
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		perm     models.RolePermission
+		action   string
+		resource string
+		want     bool
+	}{
+		{name: "exact", perm: models.RolePermission{Action: "read", Resource: "invoices"}, action: "read", resource: "invoices", want: true},
+		{name: "other action", perm: models.RolePermission{Action: "read", Resource: "invoices"}, action: "write", resource: "invoices", want: false},
+		{name: "other resource", perm: models.RolePermission{Action: "read", Resource: "invoices"}, action: "read", resource: "orders", want: false},
+		{name: "any action", perm: models.RolePermission{Action: "*", Resource: "invoices"}, action: "delete", resource: "invoices", want: true},
+		{name: "any resource", perm: models.RolePermission{Action: "read", Resource: "*"}, action: "read", resource: "orders:7", want: true},
+		{name: "any action and resource", perm: models.RolePermission{Action: "*", Resource: "*"}, action: "delete", resource: "orders:7", want: true},
+		{name: "prefix", perm: models.RolePermission{Action: "read", Resource: "invoices:*"}, action: "read", resource: "invoices:42", want: true},
+		{name: "prefix matches empty rest", perm: models.RolePermission{Action: "read", Resource: "invoices:*"}, action: "read", resource: "invoices:", want: true},
+		{name: "prefix needs the separator", perm: models.RolePermission{Action: "read", Resource: "invoices:*"}, action: "read", resource: "invoices", want: false},
+		{name: "prefix is not a substring match", perm: models.RolePermission{Action: "read", Resource: "invoices:*"}, action: "read", resource: "old-invoices:42", want: false},
+		{name: "prefix of another resource", perm: models.RolePermission{Action: "read", Resource: "invoice*"}, action: "read", resource: "invoices-archive", want: true},
+		{name: "wildcard only at the end", perm: models.RolePermission{Action: "read", Resource: "*:42"}, action: "read", resource: "invoices:42", want: false},
+		{name: "action is not a prefix", perm: models.RolePermission{Action: "read*", Resource: "*"}, action: "readall", resource: "invoices", want: false},
+		{name: "case sensitive", perm: models.RolePermission{Action: "read", Resource: "invoices"}, action: "READ", resource: "invoices", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchPermission(tt.perm, tt.action, tt.resource); got != tt.want {
+				t.Errorf("matchPermission(%s on %s, %q, %q) = %v, want %v", tt.perm.Action, tt.perm.Resource, tt.action, tt.resource, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDecideRejectsBadInput covers the checks decide makes before it looks
+// up any role, so it runs without a database.
+func TestDecideRejectsBadInput(t *testing.T) {
+	s := &AuthzService{jwtCfg: config.JWTConfig{AccessSecret: "access-secret"}}
+
+	forged, err := security.SignAccessToken(security.AccessClaims{UserID: 1}, "other-secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := security.SignAccessToken(security.AccessClaims{UserID: 1}, "access-secret", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		check      AuthzCheck
+		wantReason string
+	}{
+		{name: "no action", check: AuthzCheck{Subject: 1, Resource: "invoices"}, wantReason: "action and resource are required"},
+		{name: "no resource", check: AuthzCheck{Subject: 1, Action: "read"}, wantReason: "action and resource are required"},
+		{name: "blank action", check: AuthzCheck{Subject: 1, Action: "  ", Resource: "invoices"}, wantReason: "action and resource are required"},
+		{name: "no subject", check: AuthzCheck{Action: "read", Resource: "invoices"}, wantReason: "token or subject is required"},
+		{name: "garbage token", check: AuthzCheck{Token: "not-a-jwt", Action: "read", Resource: "invoices"}, wantReason: "invalid or expired token"},
+		{name: "token signed with another secret", check: AuthzCheck{Token: forged, Action: "read", Resource: "invoices"}, wantReason: "invalid or expired token"},
+		{name: "expired token", check: AuthzCheck{Token: expired, Action: "read", Resource: "invoices"}, wantReason: "invalid or expired token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := s.decide(context.Background(), tt.check)
+			if err != nil {
+				t.Fatalf("decide: %v", err)
+			}
+			if decision.Allowed {
+				t.Errorf("decide allowed %+v", tt.check)
+			}
+			if decision.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", decision.Reason, tt.wantReason)
+			}
+		})
+	}
+}
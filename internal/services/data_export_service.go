@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+	"github.com/google/uuid"
+)
+
+const (
+	dataExportTTL    = 24 * time.Hour
+	dataExportWindow = 15 * time.Minute
+	dataExportBuild  = 2 * time.Minute
+)
+
+var ErrExportThrottled = errors.New("a data export was requested recently")
+
+type auditLogExport struct {
+	Event         string          `json:"event"`
+	PrincipalType string          `json:"principal_type"`
+	IP            string          `json:"ip"`
+	UserAgent     string          `json:"user_agent"`
+	Browser       string          `json:"browser,omitempty"`
+	OS            string          `json:"os,omitempty"`
+	DeviceType    string          `json:"device_type,omitempty"`
+	Country       string          `json:"country,omitempty"`
+	City          string          `json:"city,omitempty"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// DataExportService builds "download my data" archives in the background
+// and emails the user a time-limited download link.
+type DataExportService struct {
+	exportRepo  *repositories.DataExportRepository
+	userRepo    *repositories.UserRepository
+	orgRepo     *repositories.OrganizationRepository
+	apiKeyRepo  *repositories.APIKeyRepository
+	sessionRepo *repositories.SessionRepository
+	auditRepo   *repositories.AuditRepo
+	mailer      Mailer
+	// publicURL is where users reach this service; download links point
+	// at it.
+	publicURL string
+}
+
+func NewDataExportService(exportRepo *repositories.DataExportRepository, userRepo *repositories.UserRepository, orgRepo *repositories.OrganizationRepository, apiKeyRepo *repositories.APIKeyRepository, sessionRepo *repositories.SessionRepository, auditRepo *repositories.AuditRepo, mailer Mailer, publicURL string) *DataExportService {
+	return &DataExportService{
+		exportRepo:  exportRepo,
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		apiKeyRepo:  apiKeyRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		mailer:      mailer,
+		publicURL:   strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// RequestExport queues an export of everything held about the user. Only
+// one export can be requested per user every dataExportWindow.
+func (s *DataExportService) RequestExport(userID uint, ip, ua string) (*repositories.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	claimed, err := s.exportRepo.ClaimUserSlot(ctx, userID, dataExportWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrExportThrottled
+	}
+
+	exportID := uuid.NewString()
+	if err := s.exportRepo.Create(ctx, exportID, userID, dataExportTTL); err != nil {
+		return nil, err
+	}
+
+	s.auditRepo.LogWithMetadata("DATA_EXPORT_REQUESTED", &userID, ip, ua, map[string]any{
+		"export_id": exportID,
+	})
+
+	go s.build(exportID, userID)
+
+	return s.exportRepo.Get(ctx, exportID)
+}
+
+func (s *DataExportService) build(exportID string, userID uint) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportBuild)
+	defer cancel()
+
+	archive, err := s.collect(ctx, userID)
+	if err != nil {
+		log.Printf("data export %s failed: %v", exportID, err)
+		_ = s.exportRepo.MarkFailed(ctx, exportID)
+		return
+	}
+
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		_ = s.exportRepo.MarkFailed(ctx, exportID)
+		return
+	}
+	rowToken := base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	if err := s.exportRepo.MarkReady(ctx, exportID, archive, rowToken, dataExportTTL); err != nil {
+		log.Printf("data export %s failed: %v", exportID, err)
+		_ = s.exportRepo.MarkFailed(ctx, exportID)
+		return
+	}
+
+	if err := s.sendReady(ctx, userID, rowToken); err != nil {
+		// the download token only exists in the email
+		log.Printf("data export %s email failed: %v", exportID, err)
+		_ = s.exportRepo.MarkFailed(ctx, exportID)
+		return
+	}
+
+	s.auditRepo.LogWithMetadata("DATA_EXPORT_READY", &userID, "", "", map[string]any{
+		"export_id": exportID,
+	})
+}
+
+func (s *DataExportService) sendReady(ctx context.Context, userID uint, rowToken string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNotFound
+	}
+
+	link := s.publicURL + "/auth/account/export/download?token=" + url.QueryEscape(rowToken)
+
+	var b strings.Builder
+	b.WriteString("The export of your account data you requested is ready.\n\n")
+	b.WriteString("Download it here:\n")
+	fmt.Fprintf(&b, "%s\n\n", link)
+	fmt.Fprintf(&b, "The link expires in %d hours. If you did not request this export, change your password.\n", int(dataExportTTL.Hours()))
+
+	return s.mailer.Send(ctx, user.Email, "Your data export is ready", b.String())
+}
+
+// collect gathers the user's data into one JSON document.
+func (s *DataExportService) collect(ctx context.Context, userID uint) ([]byte, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	orgs, err := s.orgRepo.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := s.apiKeyRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.ListByUsers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.auditRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs := make([]auditLogExport, 0, len(logs))
+	for _, entry := range logs {
+		auditLogs = append(auditLogs, auditLogExport{
+			Event:         entry.Event,
+			PrincipalType: entry.PrincipalType,
+			IP:            entry.IP,
+			UserAgent:     entry.UserAgent,
+			Browser:       entry.Browser,
+			OS:            entry.OS,
+			DeviceType:    entry.DeviceType,
+			Country:       entry.Country,
+			City:          entry.City,
+			Metadata:      entry.Metadata,
+			CreatedAt:     entry.CreatedAt,
+		})
+	}
+
+	return json.Marshal(map[string]any{
+		"generated_at":  time.Now().UTC(),
+		"profile":       user,
+		"organizations": orgs,
+		"api_keys":      apiKeys,
+		"sessions":      sessions,
+		"audit_logs":    auditLogs,
+	})
+}
+
+// GetExport returns the status of one of the user's exports.
+func (s *DataExportService) GetExport(userID uint, exportID string) (*repositories.DataExport, error) {
+	export, err := s.exportRepo.Get(context.Background(), exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || export.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return export, nil
+}
+
+// Download returns the archive for a download token. The token is the only
+// credential, so it works from an email link without a session.
+func (s *DataExportService) Download(rowToken, ip, ua string) (*repositories.DataExport, []byte, error) {
+	if rowToken == "" {
+		return nil, nil, ErrInvalidInput
+	}
+
+	export, archive, err := s.exportRepo.GetArchiveByToken(context.Background(), rowToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export == nil {
+		s.auditRepo.Log("DATA_EXPORT_TOKEN_INVALID", nil, ip, ua)
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	s.auditRepo.LogWithMetadata("DATA_EXPORT_DOWNLOADED", &export.UserID, ip, ua, map[string]any{
+		"export_id": export.ID,
+	})
+
+	return export, archive, nil
+}
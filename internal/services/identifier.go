@@ -0,0 +1,73 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+)
+
+const (
+	IdentifierEmail    = "email"
+	IdentifierUsername = "username"
+	IdentifierPhone    = "phone"
+)
+
+var (
+	// usernames start with a letter so they can never look like a phone
+	// number or an email address
+	usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9._-]{2,31}$`)
+	e164Pattern     = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+// normalizeUsername lowercases a username and reports whether it is valid.
+func normalizeUsername(raw string) (string, bool) {
+	username := strings.ToLower(strings.TrimSpace(raw))
+	return username, usernamePattern.MatchString(username)
+}
+
+// normalizePhone strips common separators ("+1 (555) 010-0000") and reports
+// whether the result is an E.164 number.
+func normalizePhone(raw string) (string, bool) {
+	phone := phoneSeparators.Replace(strings.TrimSpace(raw))
+	return phone, e164Pattern.MatchString(phone)
+}
+
+// normalizeIdentifier works out which kind of login identifier raw is: an
+// email contains "@", a phone number starts with "+", anything else is a
+// username.
+func normalizeIdentifier(raw string) (kind, identifier string, ok bool) {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case strings.Contains(raw, "@"):
+		email := strings.ToLower(raw)
+		return IdentifierEmail, email, email != ""
+	case strings.HasPrefix(raw, "+"):
+		phone, ok := normalizePhone(raw)
+		return IdentifierPhone, phone, ok
+	default:
+		username, ok := normalizeUsername(raw)
+		return IdentifierUsername, username, ok
+	}
+}
+
+// findByIdentifier looks the user up by email, username or phone number.
+// An identifier that cannot be valid finds nobody.
+func (s *AuthService) findByIdentifier(raw string) (*models.UserModel, error) {
+	kind, identifier, ok := normalizeIdentifier(raw)
+	if !ok {
+		return nil, nil
+	}
+
+	switch kind {
+	case IdentifierPhone:
+		return s.userRepo.FindByPhone(identifier)
+	case IdentifierUsername:
+		return s.userRepo.FindByUsername(identifier)
+	default:
+		return s.userRepo.FindByEmail(identifier)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/google/uuid"
+)
+
+type Impersonation struct {
+	ID          string    `json:"impersonation_id"`
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int64     `json:"expires_in"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func impersonationKey(id string) string {
+	return fmt.Sprintf("impersonation:%s", id)
+}
+
+// StartImpersonation mints a short-lived access token for targetID that
+// names adminID in its "act" claim. No refresh token or session is created,
+// so the impersonation cannot outlive the token.
+func (s *AuthService) StartImpersonation(adminID, targetID uint, reason, ip, ua string) (*Impersonation, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || adminID == targetID {
+		return nil, ErrInvalidInput
+	}
+
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil || admin.Role != models.Admin {
+		return nil, ErrForbidden
+	}
+
+	target, err := s.userRepo.FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrNotFound
+	}
+
+	// admins cannot be impersonated, so impersonation never escalates
+	if target.Role == models.Admin {
+		return nil, ErrForbidden
+	}
+
+	membership, err := s.orgRepo.DefaultMembership(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.jwtCfg.ImpersonationTTL
+	impersonationID := uuid.NewString()
+
+	claims := s.accessClaims(target, membership)
+	claims.Act = &security.ActorClaim{
+		UserID: admin.ID,
+		Email:  admin.Email,
+	}
+	claims.Impersonating = true
+	claims.ImpersonationID = impersonationID
+
+	accessToken, err := security.SignAccessToken(claims, s.jwtCfg.AccessSecret, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.sessionRepo.Redis().Set(ctx, impersonationKey(impersonationID), adminID, ttl).Err(); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	s.auditRepo.LogWithMetadata("IMPERSONATION_START", &target.ID, ip, ua, map[string]any{
+		"actor_id":         admin.ID,
+		"actor_email":      admin.Email,
+		"impersonation_id": impersonationID,
+		"reason":           reason,
+		"expires_at":       expiresAt,
+	})
+
+	return &Impersonation{
+		ID:          impersonationID,
+		AccessToken: accessToken,
+		ExpiresIn:   int64(ttl.Seconds()),
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// StopImpersonation ends an impersonation early; its token is rejected by
+// security.ImpersonationGuard from then on.
+func (s *AuthService) StopImpersonation(impersonationID string, adminID, targetID uint, ip, ua string) error {
+	ctx := context.Background()
+
+	deleted, err := s.sessionRepo.Redis().Del(ctx, impersonationKey(impersonationID)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	s.auditRepo.LogWithMetadata("IMPERSONATION_STOP", &targetID, ip, ua, map[string]any{
+		"actor_id":         adminID,
+		"impersonation_id": impersonationID,
+	})
+
+	return nil
+}
+
+func (s *AuthService) IsImpersonationActive(impersonationID string) bool {
+	exists, err := s.sessionRepo.Redis().Exists(context.Background(), impersonationKey(impersonationID)).Result()
+	return err == nil && exists == 1
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	// loginHistoryTTL is how long a device or country stays known without
+	// logins from it.
+	loginHistoryTTL = 180 * 24 * time.Hour
+	loginReportTTL  = 7 * 24 * time.Hour
+)
+
+// Mailer delivers a plain-text email.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LoginAlertService emails users when they log in from a device or country
+// not seen before, with a link to report the login if it was not them.
+type LoginAlertService struct {
+	alertRepo   *repositories.LoginAlertRepository
+	userRepo    *repositories.UserRepository
+	sessionRepo *repositories.SessionRepository
+	auditRepo   *repositories.AuditRepo
+	mailer      Mailer
+	authService *AuthService
+	// publicURL is where users reach this service; report links point at
+	// it.
+	publicURL string
+}
+
+// NewLoginAlertService needs authService to send the password reset of a
+// reported login; hook the alerts into logins with authService.SetLoginAlerts.
+func NewLoginAlertService(alertRepo *repositories.LoginAlertRepository, userRepo *repositories.UserRepository, sessionRepo *repositories.SessionRepository, auditRepo *repositories.AuditRepo, mailer Mailer, authService *AuthService, publicURL string) *LoginAlertService {
+	s := &LoginAlertService{
+		alertRepo:   alertRepo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		mailer:      mailer,
+		authService: authService,
+		publicURL:   strings.TrimRight(publicURL, "/"),
+	}
+	return s
+}
+
+// deviceFingerprint identifies a device coarsely enough that browser and OS
+// updates do not make it look new.
+func deviceFingerprint(browser, os, deviceType string) string {
+	return strings.ToLower(fmt.Sprintf("%s|%s|%s", browser, os, deviceType))
+}
+
+// CheckLogin compares the new session with the user's login history and
+// sends an alert if its device or country is new. The very first login
+// only records the history. Errors are logged, never returned: an alert
+// must not hold up or fail the login.
+func (s *LoginAlertService) CheckLogin(user *models.UserModel, sessionID, ip, ua string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info, err := s.sessionRepo.Get(ctx, sessionID)
+	if err != nil || info == nil {
+		return
+	}
+
+	history, err := s.alertRepo.Record(ctx, user.ID, deviceFingerprint(info.Browser, info.OS, info.DeviceType), info.Location.Country, loginHistoryTTL)
+	if err != nil {
+		log.Printf("login history for user %d failed: %v", user.ID, err)
+		return
+	}
+	if history.FirstLogin || (!history.NewDevice && !history.NewCountry) {
+		return
+	}
+
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		return
+	}
+	rowToken := base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	if err := s.alertRepo.StoreReport(ctx, rowToken, user.ID, sessionID, loginReportTTL); err != nil {
+		log.Printf("login report token for user %d failed: %v", user.ID, err)
+		return
+	}
+
+	if err := s.mailer.Send(ctx, user.Email, "New sign-in to your account", s.alertBody(info, rowToken)); err != nil {
+		log.Printf("login alert for user %d failed: %v", user.ID, err)
+		return
+	}
+
+	s.auditRepo.LogWithMetadata("LOGIN_ALERT_SENT", &user.ID, ip, ua, map[string]any{
+		"session_id":  sessionID,
+		"new_device":  history.NewDevice,
+		"new_country": history.NewCountry,
+	})
+}
+
+func (s *LoginAlertService) alertBody(info *repositories.SessionInfo, rowToken string) string {
+	device := fmt.Sprintf("%s %s on %s %s (%s)", info.Browser, info.BrowserVersion, info.OS, info.OSVersion, info.DeviceType)
+
+	location := "unknown"
+	switch {
+	case info.Location.City != "" && info.Location.CountryName != "":
+		location = info.Location.City + ", " + info.Location.CountryName
+	case info.Location.CountryName != "":
+		location = info.Location.CountryName
+	}
+
+	link := s.publicURL + "/auth/login-alerts/report?token=" + url.QueryEscape(rowToken)
+
+	var b strings.Builder
+	b.WriteString("Your account was just signed in to from a new device or location.\n\n")
+	fmt.Fprintf(&b, "Time:     %s\n", time.Unix(info.StartedAt, 0).UTC().Format(time.RFC1123))
+	fmt.Fprintf(&b, "Device:   %s\n", strings.Join(strings.Fields(device), " "))
+	fmt.Fprintf(&b, "Location: %s\n", location)
+	fmt.Fprintf(&b, "IP:       %s\n\n", info.IP)
+	b.WriteString("If this was you, you can ignore this email.\n\n")
+	b.WriteString("If it wasn't you, open this link to sign out all sessions and reset your password:\n")
+	fmt.Fprintf(&b, "%s\n\n", link)
+	fmt.Fprintf(&b, "The link works once and expires in %d days.\n", int(loginReportTTL.Hours()/24))
+	return b.String()
+}
+
+// ReportLogin handles the "this wasn't me" link. The reported session may
+// have been refreshed under a new ID since, so every session is revoked;
+// the account is then locked until the password is reset, and a reset
+// token is sent.
+func (s *LoginAlertService) ReportLogin(rowToken, ip, ua string) error {
+	if rowToken == "" {
+		return ErrInvalidInput
+	}
+
+	ctx := context.Background()
+
+	userID, sessionID, err := s.alertRepo.TakeReport(ctx, rowToken)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		return ErrInvalidCredentials
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNotFound
+	}
+
+	if err := s.userRepo.SetMustResetPassword(user.ID, true); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteAll(ctx, user.ID, ""); err != nil {
+		return err
+	}
+
+	if err := s.authService.RequestPasswordReset(user.Email, ip, ua); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("LOGIN_REPORTED_NOT_ME", &user.ID, ip, ua, map[string]any{
+		"session_id": sessionID,
+	})
+
+	return nil
+}
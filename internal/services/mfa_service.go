@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	MFAMethodSMS = "sms"
+
+	otpPurposeLogin       = "login"
+	otpPurposeVerifyPhone = "verify_phone"
+
+	smsCodeTTL        = 5 * time.Minute
+	smsResendInterval = time.Minute
+	smsMaxAttempts    = 5
+	smsDailyLimit     = 10
+	mfaChallengeTTL   = 10 * time.Minute
+)
+
+var (
+	ErrOTPThrottled     = errors.New("a code was sent too recently")
+	ErrOTPExpired       = errors.New("code expired or used up")
+	ErrPhoneNotVerified = errors.New("phone number not verified")
+	ErrMFAEnabled       = errors.New("two-factor authentication is enabled")
+)
+
+// SMSSender delivers a text message to an E.164 phone number.
+type SMSSender interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// MFARequiredError is returned by Login when the password was correct but
+// the user still has to pass a second factor. Token identifies the pending
+// login in VerifyChallenge.
+type MFARequiredError struct {
+	Token     string   `json:"mfa_token"`
+	Methods   []string `json:"methods"`
+	ExpiresIn int64    `json:"expires_in"`
+}
+
+func (e *MFARequiredError) Error() string {
+	return "second factor required"
+}
+
+// MFAService handles SMS one-time passcodes: phone verification, turning
+// SMS two-factor on and off, and the second step of Login.
+type MFAService struct {
+	userRepo    *repositories.UserRepository
+	otpRepo     *repositories.OTPRepository
+	sender      SMSSender
+	auditRepo   *repositories.AuditRepo
+	authService *AuthService
+}
+
+// NewMFAService needs authService to finish logins that passed the second
+// factor; hook it into Login with authService.SetMFA.
+func NewMFAService(userRepo *repositories.UserRepository, otpRepo *repositories.OTPRepository, sender SMSSender, auditRepo *repositories.AuditRepo, authService *AuthService) *MFAService {
+	s := &MFAService{
+		userRepo:    userRepo,
+		otpRepo:     otpRepo,
+		sender:      sender,
+		auditRepo:   auditRepo,
+		authService: authService,
+	}
+	return s
+}
+
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// sendCode texts a new code to the user's phone, subject to the resend
+// interval and the daily limit.
+func (s *MFAService) sendCode(ctx context.Context, user *models.UserModel, purpose string) error {
+	if user.Phone == nil {
+		return ErrInvalidInput
+	}
+
+	claimed, err := s.otpRepo.ClaimResend(ctx, purpose, user.ID, smsResendInterval)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrOTPThrottled
+	}
+
+	sent, err := s.otpRepo.CountSent(ctx, user.ID, 24*time.Hour)
+	if err != nil {
+		return err
+	}
+	if sent > smsDailyLimit {
+		return ErrOTPThrottled
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return err
+	}
+	if err := s.otpRepo.Store(ctx, purpose, user.ID, code, smsCodeTTL); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(smsCodeTTL.Minutes()))
+	return s.sender.Send(ctx, *user.Phone, message)
+}
+
+func (s *MFAService) findUser(userID uint) (*models.UserModel, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+/* ============================
+   Login second step
+============================ */
+
+// StartChallenge opens a pending login for a user who passed the password
+// step and texts them a code.
+func (s *MFAService) StartChallenge(user *models.UserModel, ip, ua string) (*MFARequiredError, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		return nil, err
+	}
+	rowToken := base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	if err := s.otpRepo.CreateChallenge(ctx, rowToken, user.ID, mfaChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	// a code sent moments ago is still valid, so throttling is not an error
+	// here
+	if err := s.sendCode(ctx, user, otpPurposeLogin); err != nil && !errors.Is(err, ErrOTPThrottled) {
+		log.Printf("sms code for user %d failed: %v", user.ID, err)
+	}
+
+	s.auditRepo.LogWithMetadata("MFA_CHALLENGE_STARTED", &user.ID, ip, ua, map[string]any{
+		"method": MFAMethodSMS,
+	})
+
+	return &MFARequiredError{
+		Token:     rowToken,
+		Methods:   []string{MFAMethodSMS},
+		ExpiresIn: int64(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// ResendChallenge texts a fresh code for a pending login.
+func (s *MFAService) ResendChallenge(rowToken, ip, ua string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID, err := s.otpRepo.GetChallenge(ctx, rowToken)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		return ErrInvalidCredentials
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sendCode(ctx, user, otpPurposeLogin); err != nil {
+		return err
+	}
+
+	s.auditRepo.Log("MFA_CODE_RESENT", &user.ID, ip, ua)
+	return nil
+}
+
+// VerifyChallenge finishes a pending login with the texted code. With
+// rememberDevice the device is trusted, so later logins from it skip the
+// code.
+func (s *MFAService) VerifyChallenge(rowToken, code string, rememberDevice bool, ip, ua string) (*TokenPair, error) {
+	if rowToken == "" || code == "" {
+		return nil, ErrInvalidInput
+	}
+
+	ctx := context.Background()
+
+	userID, err := s.otpRepo.GetChallenge(ctx, rowToken)
+	if err != nil {
+		return nil, err
+	}
+	if userID == 0 {
+		return nil, ErrOTPExpired
+	}
+
+	result, err := s.otpRepo.Verify(ctx, otpPurposeLogin, userID, code, smsMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch result {
+	case repositories.OTPValid:
+	case repositories.OTPInvalid:
+		s.auditRepo.Log("MFA_FAILED", &userID, ip, ua)
+		return nil, ErrInvalidCredentials
+	default:
+		// out of attempts: the whole login has to start over
+		_ = s.otpRepo.DeleteChallenge(ctx, rowToken)
+		s.auditRepo.Log("MFA_FAILED", &userID, ip, ua)
+		return nil, ErrOTPExpired
+	}
+
+	_ = s.otpRepo.DeleteChallenge(ctx, rowToken)
+
+	// the account may have changed while the code was pending
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authService.checkLoginAllowed(user, ip, ua); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.authService.issueSession(user, ip, ua, []string{AMRPassword, AMRSMS, AMRMFA})
+	if err != nil {
+		return nil, err
+	}
+
+	if rememberDevice {
+		trusted, err := s.authService.rememberDevice(user, ip, ua)
+		if err != nil {
+			log.Printf("remembering device for user %d failed: %v", user.ID, err)
+		} else if trusted != "" {
+			tokens.TrustedDeviceToken = trusted
+			tokens.TrustedDeviceTTL = s.authService.jwtCfg.TrustedDeviceTTL
+		}
+	}
+
+	return tokens, nil
+}
+
+/* ============================
+   Phone verification and enrollment
+============================ */
+
+func (s *MFAService) StartPhoneVerification(userID uint, ip, ua string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sendCode(context.Background(), user, otpPurposeVerifyPhone); err != nil {
+		return err
+	}
+
+	s.auditRepo.Log("PHONE_VERIFICATION_SENT", &user.ID, ip, ua)
+	return nil
+}
+
+func (s *MFAService) ConfirmPhone(userID uint, code, ip, ua string) error {
+	if code == "" {
+		return ErrInvalidInput
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.Phone == nil {
+		return ErrInvalidInput
+	}
+
+	result, err := s.otpRepo.Verify(context.Background(), otpPurposeVerifyPhone, user.ID, code, smsMaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	switch result {
+	case repositories.OTPValid:
+	case repositories.OTPInvalid:
+		s.auditRepo.Log("PHONE_VERIFICATION_FAILED", &user.ID, ip, ua)
+		return ErrInvalidCredentials
+	default:
+		return ErrOTPExpired
+	}
+
+	verified, err := s.userRepo.MarkPhoneVerified(user.ID, *user.Phone, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		// the number changed after the code was sent
+		return ErrOTPExpired
+	}
+
+	s.auditRepo.Log("PHONE_VERIFIED", &user.ID, ip, ua)
+	return nil
+}
+
+// EnableSMS turns on SMS two-factor. The password is required again and the
+// phone must be verified first.
+func (s *MFAService) EnableSMS(userID uint, password, ip, ua string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !verifyPassword(user.Password, password) {
+		s.auditRepo.Log("MFA_REAUTH_FAILED", &user.ID, ip, ua)
+		return ErrInvalidCredentials
+	}
+
+	if user.Phone == nil || user.PhoneVerifiedAt == nil {
+		return ErrPhoneNotVerified
+	}
+
+	if err := s.userRepo.SetSMSMFA(user.ID, true); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("MFA_ENABLED", &user.ID, ip, ua, map[string]any{
+		"method": MFAMethodSMS,
+	})
+	return nil
+}
+
+func (s *MFAService) DisableSMS(userID uint, password, ip, ua string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !verifyPassword(user.Password, password) {
+		s.auditRepo.Log("MFA_REAUTH_FAILED", &user.ID, ip, ua)
+		return ErrInvalidCredentials
+	}
+
+	if err := s.userRepo.SetSMSMFA(user.ID, false); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("MFA_DISABLED", &user.ID, ip, ua, map[string]any{
+		"method": MFAMethodSMS,
+	})
+	return nil
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+var (
+	ErrOrgExists       = errors.New("organization slug already taken")
+	ErrLastOwner       = errors.New("organization must keep at least one owner")
+	ErrAlreadyMember   = errors.New("user is already a member")
+	ErrInvalidInvite   = errors.New("invalid or expired invitation")
+	ErrPasswordMissing = errors.New("password required to create account")
+)
+
+const inviteTTL = 7 * 24 * time.Hour
+
+var slugCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+
+type OrgService struct {
+	orgRepo     *repositories.OrganizationRepository
+	inviteRepo  *repositories.InvitationRepository
+	userRepo    *repositories.UserRepository
+	authService *AuthService
+	auditRepo   *repositories.AuditRepo
+	mailer      Mailer
+	// frontendURL hosts the page invitation links open.
+	frontendURL string
+}
+
+func NewOrgService(orgRepo *repositories.OrganizationRepository, inviteRepo *repositories.InvitationRepository, userRepo *repositories.UserRepository, authService *AuthService, auditRepo *repositories.AuditRepo, mailer Mailer, frontendURL string) *OrgService {
+	return &OrgService{
+		orgRepo:     orgRepo,
+		inviteRepo:  inviteRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		auditRepo:   auditRepo,
+		mailer:      mailer,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+func slugify(s string) string {
+	s = slugCleaner.ReplaceAllString(strings.ToLower(strings.TrimSpace(s)), "-")
+	return strings.Trim(s, "-")
+}
+
+func (s *OrgService) CreateOrganization(userID uint, name, slug, ip, ua string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if slug == "" {
+		slug = name
+	}
+	slug = slugify(slug)
+
+	if name == "" || slug == "" {
+		return nil, ErrInvalidInput
+	}
+
+	existing, err := s.orgRepo.FindBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOrgExists
+	}
+
+	org := &models.Organization{
+		Name: name,
+		Slug: slug,
+	}
+
+	if err := s.orgRepo.Create(org, userID); err != nil {
+		return nil, err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_CREATED", &userID, ip, ua, map[string]any{
+		"org_id": org.ID,
+	})
+
+	return org, nil
+}
+
+func (s *OrgService) ListOrganizations(userID uint) ([]models.OrgMembership, error) {
+	return s.orgRepo.ListForUser(userID)
+}
+
+// requireOrgRole checks the actor's membership in Postgres rather than
+// trusting the token, so role changes apply immediately.
+func (s *OrgService) requireOrgRole(orgID, actorID uint, roles ...models.OrgRole) (*models.Membership, error) {
+	membership, err := s.orgRepo.ActiveMembership(orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, ErrNotFound
+	}
+
+	if len(roles) == 0 {
+		return membership, nil
+	}
+
+	for _, role := range roles {
+		if membership.Role == role {
+			return membership, nil
+		}
+	}
+
+	return nil, ErrForbidden
+}
+
+func (s *OrgService) ListMembers(orgID, actorID uint) ([]repositories.OrgMemberInfo, error) {
+	if _, err := s.requireOrgRole(orgID, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.ListMembers(orgID)
+}
+
+func (s *OrgService) UpdateMemberRole(orgID, actorID, targetID uint, role models.OrgRole, ip, ua string) error {
+	if !role.Valid() {
+		return ErrInvalidInput
+	}
+
+	actor, err := s.requireOrgRole(orgID, actorID, models.OrgOwner, models.OrgAdmin)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.orgRepo.GetMembership(orgID, targetID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrNotFound
+	}
+
+	// only owners may grant or take away ownership
+	if (role == models.OrgOwner || target.Role == models.OrgOwner) && actor.Role != models.OrgOwner {
+		return ErrForbidden
+	}
+
+	if target.Role == models.OrgOwner && role != models.OrgOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgRepo.UpdateMemberRole(orgID, targetID, role); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_MEMBER_ROLE_CHANGED", &targetID, ip, ua, map[string]any{
+		"org_id":   orgID,
+		"actor_id": actorID,
+		"old_role": target.Role,
+		"new_role": role,
+	})
+
+	return nil
+}
+
+func (s *OrgService) RemoveMember(orgID, actorID, targetID uint, ip, ua string) error {
+	target, err := s.orgRepo.GetMembership(orgID, targetID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrNotFound
+	}
+
+	// members may always leave; removing someone else needs an org admin
+	if actorID != targetID {
+		actor, err := s.requireOrgRole(orgID, actorID, models.OrgOwner, models.OrgAdmin)
+		if err != nil {
+			return err
+		}
+		if target.Role == models.OrgOwner && actor.Role != models.OrgOwner {
+			return ErrForbidden
+		}
+	}
+
+	if target.Role == models.OrgOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgRepo.RemoveMember(orgID, targetID); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_MEMBER_REMOVED", &targetID, ip, ua, map[string]any{
+		"org_id":   orgID,
+		"actor_id": actorID,
+	})
+
+	return nil
+}
+
+func (s *OrgService) ensureAnotherOwner(orgID uint) error {
+	owners, err := s.orgRepo.CountOwners(orgID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+/* ============================
+   Invitations
+============================ */
+
+func (s *OrgService) CreateInvitation(orgID, actorID uint, email string, role models.OrgRole, ip, ua string) (*models.OrgInvitation, error) {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if role == "" {
+		role = models.OrgMember
+	}
+
+	if _, err := mail.ParseAddress(email); err != nil || !role.Valid() {
+		return nil, ErrInvalidInput
+	}
+
+	actor, err := s.requireOrgRole(orgID, actorID, models.OrgOwner, models.OrgAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.OrgOwner && actor.Role != models.OrgOwner {
+		return nil, ErrForbidden
+	}
+
+	existing, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		membership, err := s.orgRepo.GetMembership(orgID, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if membership != nil {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		return nil, err
+	}
+	rowToken := base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	inv := &models.OrgInvitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      &actorID,
+		ExpiresAt:      time.Now().Add(inviteTTL),
+	}
+
+	if err := s.inviteRepo.Create(inv, rowToken); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendInvitation(inv, rowToken); err != nil {
+		// nobody can use an invitation whose token was never delivered
+		log.Printf("invitation %d email failed: %v", inv.ID, err)
+		_ = s.inviteRepo.Revoke(inv.ID)
+		return nil, err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_INVITE_CREATED", &actorID, ip, ua, map[string]any{
+		"org_id":    orgID,
+		"invite_id": inv.ID,
+		"email":     email,
+		"role":      role,
+	})
+
+	return inv, nil
+}
+
+func (s *OrgService) sendInvitation(inv *models.OrgInvitation, rowToken string) error {
+	org, err := s.orgRepo.FindByID(inv.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if org == nil {
+		return ErrNotFound
+	}
+
+	link := s.frontendURL + "/invitations/accept?token=" + url.QueryEscape(rowToken)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "You have been invited to join %s as %s.\n\n", org.Name, inv.Role)
+	b.WriteString("Open this link to accept the invitation:\n")
+	fmt.Fprintf(&b, "%s\n\n", link)
+	fmt.Fprintf(&b, "The link works once and expires in %d days.\n", int(inviteTTL.Hours()/24))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return s.mailer.Send(ctx, inv.Email, "You're invited to join "+org.Name, b.String())
+}
+
+func (s *OrgService) ListInvitations(orgID, actorID uint) ([]models.OrgInvitation, error) {
+	if _, err := s.requireOrgRole(orgID, actorID, models.OrgOwner, models.OrgAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.ListByOrg(orgID)
+}
+
+func (s *OrgService) RevokeInvitation(orgID, actorID, inviteID uint, ip, ua string) error {
+	if _, err := s.requireOrgRole(orgID, actorID, models.OrgOwner, models.OrgAdmin); err != nil {
+		return err
+	}
+
+	inv, err := s.inviteRepo.FindByID(orgID, inviteID)
+	if err != nil {
+		return err
+	}
+	if inv == nil {
+		return ErrNotFound
+	}
+
+	if err := s.inviteRepo.Revoke(inv.ID); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_INVITE_REVOKED", &actorID, ip, ua, map[string]any{
+		"org_id":    orgID,
+		"invite_id": inv.ID,
+	})
+
+	return nil
+}
+
+// AcceptInvitation links the invited email's existing account to the
+// organization, or registers a new account with password first.
+func (s *OrgService) AcceptInvitation(rowToken, password, ip, ua string) (*models.OrgInvitation, error) {
+	inv, err := s.inviteRepo.FindByToken(rowToken)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || !inv.Pending() {
+		s.auditRepo.Log("ORG_INVITE_INVALID", nil, ip, ua)
+		return nil, ErrInvalidInvite
+	}
+
+	user, err := s.userRepo.FindByEmail(inv.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		if password == "" {
+			return nil, ErrPasswordMissing
+		}
+
+		if err := s.authService.Register(inv.Email, password); err != nil {
+			return nil, err
+		}
+
+		user, err = s.userRepo.FindByEmail(inv.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrNotFound
+		}
+
+		s.auditRepo.LogWithMetadata("REGISTER_VIA_INVITE", &user.ID, ip, ua, map[string]any{
+			"org_id":    inv.OrganizationID,
+			"invite_id": inv.ID,
+		})
+	}
+
+	if err := s.inviteRepo.Accept(inv, user.ID); err != nil {
+		if errors.Is(err, repositories.ErrInvitationUsed) {
+			return nil, ErrInvalidInvite
+		}
+		return nil, err
+	}
+
+	s.auditRepo.LogWithMetadata("ORG_INVITE_ACCEPTED", &user.ID, ip, ua, map[string]any{
+		"org_id":    inv.OrganizationID,
+		"invite_id": inv.ID,
+		"role":      inv.Role,
+	})
+
+	return inv, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// unusablePassword is stored for accounts that have no password yet. It is
+// not a valid hash in any supported format, so it never verifies.
+const unusablePassword = "!"
+
+// Limits on the cost of imported hashes. Every login attempt pays the cost
+// recorded in the hash, so one crafted hash could otherwise make each try
+// allocate gigabytes or run for minutes.
+const (
+	maxArgon2Memory  = 256 * 1024 // KiB
+	maxArgon2Time    = 10
+	maxArgon2Threads = 16
+	maxArgon2KeyLen  = 64
+	maxBcryptCost    = 14
+)
+
+var errUnsupportedHash = errors.New("unsupported password hash")
+
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id decodes a PHC string such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+func parseArgon2id(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errUnsupportedHash
+	}
+
+	h := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, errUnsupportedHash
+	}
+	if h.memory == 0 || h.time == 0 || h.threads == 0 {
+		return nil, errUnsupportedHash
+	}
+	if h.memory > maxArgon2Memory || h.time > maxArgon2Time || h.threads > maxArgon2Threads {
+		return nil, errUnsupportedHash
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errUnsupportedHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 || len(h.key) > maxArgon2KeyLen {
+		return nil, errUnsupportedHash
+	}
+
+	return h, nil
+}
+
+// validPasswordHash reports whether encoded is a bcrypt or argon2id hash
+// that verifyPassword understands. Used to vet pre-hashed imports.
+func validPasswordHash(encoded string) bool {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		_, err := parseArgon2id(encoded)
+		return err == nil
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost <= maxBcryptCost
+}
+
+// verifyPassword checks password against a stored bcrypt or argon2id hash.
+// Argon2id hashes only come from imported accounts; new passwords are always
+// stored as bcrypt.
+func verifyPassword(encoded, password string) bool {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		h, err := parseArgon2id(encoded)
+		if err != nil {
+			return false
+		}
+
+		key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+		return subtle.ConstantTimeCompare(key, h.key) == 1
+	}
+
+	if cost, err := bcrypt.Cost([]byte(encoded)); err != nil || cost > maxBcryptCost {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone validation must not depend on the host's zoneinfo
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	maxDisplayNameLen = 100
+	maxAvatarURLLen   = 2048
+	maxMetadataBytes  = 16 << 10
+)
+
+// BCP 47 shaped tag such as "en", "en-US" or "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ProfileUpdate carries a partial profile change; nil fields are left as is
+// and an empty string clears a field. UserMetadata is merged into the stored
+// object key by key and a null value removes the key.
+type ProfileUpdate struct {
+	Username     *string         `json:"username"`
+	Phone        *string         `json:"phone"`
+	DisplayName  *string         `json:"display_name"`
+	Locale       *string         `json:"locale"`
+	Timezone     *string         `json:"timezone"`
+	AvatarURL    *string         `json:"avatar_url"`
+	UserMetadata json.RawMessage `json:"user_metadata"`
+}
+
+// ProfileService serves the signed-in user's own profile.
+type ProfileService struct {
+	userRepo  *repositories.UserRepository
+	auditRepo *repositories.AuditRepo
+}
+
+func NewProfileService(userRepo *repositories.UserRepository, auditRepo *repositories.AuditRepo) *ProfileService {
+	return &ProfileService{
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *ProfileService) GetProfile(userID uint) (*models.UserModel, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *ProfileService) UpdateProfile(userID uint, in ProfileUpdate, ip, ua string) (*models.UserModel, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+
+	if in.Username != nil {
+		value, err := s.identifierValue(user.ID, *in.Username, normalizeUsername, s.userRepo.FindByUsername)
+		if err != nil {
+			return nil, err
+		}
+		fields["username"] = value
+	}
+
+	if in.Phone != nil {
+		value, err := s.identifierValue(user.ID, *in.Phone, normalizePhone, s.userRepo.FindByPhone)
+		if err != nil {
+			return nil, err
+		}
+
+		current := ""
+		if user.Phone != nil {
+			current = *user.Phone
+		}
+		if next, _ := value.(string); next != current {
+			// codes go to this number, so it cannot be swapped while it
+			// is a second factor
+			if user.MFASMSEnabled {
+				return nil, ErrMFAEnabled
+			}
+			fields["phone"] = value
+			fields["phone_verified_at"] = nil
+		}
+	}
+
+	if in.DisplayName != nil {
+		name := strings.TrimSpace(*in.DisplayName)
+		if len([]rune(name)) > maxDisplayNameLen {
+			return nil, ErrInvalidInput
+		}
+		fields["display_name"] = name
+	}
+
+	if in.Locale != nil {
+		locale := strings.TrimSpace(*in.Locale)
+		if locale != "" && !localePattern.MatchString(locale) {
+			return nil, ErrInvalidInput
+		}
+		fields["locale"] = locale
+	}
+
+	if in.Timezone != nil {
+		tz := strings.TrimSpace(*in.Timezone)
+		if tz != "" && !validTimezone(tz) {
+			return nil, ErrInvalidInput
+		}
+		fields["timezone"] = tz
+	}
+
+	if in.AvatarURL != nil {
+		avatar := strings.TrimSpace(*in.AvatarURL)
+		if avatar != "" && !validAvatarURL(avatar) {
+			return nil, ErrInvalidInput
+		}
+		fields["avatar_url"] = avatar
+	}
+
+	if len(in.UserMetadata) > 0 {
+		merged, err := mergeMetadata(user.UserMetadata, in.UserMetadata)
+		if err != nil {
+			return nil, err
+		}
+		fields["user_metadata"] = string(merged)
+	}
+
+	if len(fields) == 0 {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateProfile(userID, fields); err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, 0, len(fields))
+	for field := range fields {
+		changed = append(changed, field)
+	}
+	s.auditRepo.LogWithMetadata("PROFILE_UPDATED", &userID, ip, ua, map[string]any{
+		"fields": changed,
+	})
+
+	return s.GetProfile(userID)
+}
+
+// identifierValue validates a new username or phone number and makes sure no
+// other account uses it. Empty clears the identifier (NULL in the column).
+func (s *ProfileService) identifierValue(userID uint, raw string, normalize func(string) (string, bool), find func(string) (*models.UserModel, error)) (any, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	value, ok := normalize(raw)
+	if !ok {
+		return nil, ErrInvalidInput
+	}
+
+	existing, err := find(value)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != userID {
+		return nil, ErrUserExists
+	}
+
+	return value, nil
+}
+
+// validTimezone accepts IANA zone names. time.LoadLocation also takes
+// "Local", which means nothing to a client.
+func validTimezone(tz string) bool {
+	if tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+func validAvatarURL(raw string) bool {
+	if len(raw) > maxAvatarURLLen {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != "" && u.User == nil
+}
+
+// metadataObject decodes raw as a JSON object within the size limit.
+func metadataObject(raw []byte) (map[string]json.RawMessage, error) {
+	if len(raw) > maxMetadataBytes {
+		return nil, ErrInvalidInput
+	}
+
+	obj := map[string]json.RawMessage{}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, ErrInvalidInput
+	}
+	if err := json.Unmarshal(trimmed, &obj); err != nil {
+		return nil, ErrInvalidInput
+	}
+	return obj, nil
+}
+
+// mergeMetadata applies patch to current one level deep, in the spirit of
+// JSON merge patch (RFC 7396). A null patch clears everything.
+func mergeMetadata(current, patch []byte) ([]byte, error) {
+	if string(bytes.TrimSpace(patch)) == "null" {
+		return []byte("{}"), nil
+	}
+
+	changes, err := metadataObject(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]json.RawMessage{}
+	if len(current) > 0 {
+		_ = json.Unmarshal(current, &merged)
+	}
+
+	for k, v := range changes {
+		if string(bytes.TrimSpace(v)) == "null" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxMetadataBytes {
+		return nil, ErrInvalidInput
+	}
+	return out, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/geoip"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/iplist"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
+)
+
+const (
+	RiskAllow  = "allow"
+	RiskStepUp = "mfa"
+	RiskBlock  = "block"
+
+	RiskReasonNewDevice        = "new_device"
+	RiskReasonNewCountry       = "new_country"
+	RiskReasonImpossibleTravel = "impossible_travel"
+	RiskReasonIPReputation     = "ip_reputation"
+	RiskReasonFailedAttempts   = "failed_attempts"
+	RiskReasonUnusualHour      = "unusual_hour"
+
+	riskScoreNewDevice        = 20
+	riskScoreNewCountry       = 20
+	riskScoreImpossibleTravel = 50
+	riskScoreIPReputation     = 60
+	riskScorePerFailedAttempt = 10
+	riskScoreUnusualHour      = 10
+
+	// GeoIP places an address at its city at best, so shorter hops are not
+	// treated as travel at all.
+	minTravelDistanceKm = 500
+	// unusual hours are [unusualHourStart, unusualHourEnd) in the user's
+	// own timezone
+	unusualHourStart = 0
+	unusualHourEnd   = 5
+)
+
+var ErrLoginBlocked = errors.New("login blocked as too risky")
+
+// RiskAssessment is the outcome of scoring one login.
+type RiskAssessment struct {
+	Score    int
+	Reasons  []string
+	Decision string
+	// Details backs up some reasons, e.g. the distance travelled.
+	Details map[string]any
+}
+
+func (a *RiskAssessment) add(reason string, score int) {
+	a.Reasons = append(a.Reasons, reason)
+	a.Score += score
+}
+
+// RiskService scores logins that passed the password check, so Login can
+// let them through, ask for a second factor or refuse them.
+type RiskService struct {
+	alertRepo *repositories.LoginAlertRepository
+	geo       *geoip.Resolver
+	blocklist *iplist.List
+	auditRepo *repositories.AuditRepo
+	cfg       config.RiskConfig
+}
+
+// NewRiskService builds the scorer; hook it into Login with
+// AuthService.SetRisk.
+func NewRiskService(alertRepo *repositories.LoginAlertRepository, geo *geoip.Resolver, blocklist *iplist.List, auditRepo *repositories.AuditRepo, cfg config.RiskConfig) *RiskService {
+	return &RiskService{
+		alertRepo: alertRepo,
+		geo:       geo,
+		blocklist: blocklist,
+		auditRepo: auditRepo,
+		cfg:       cfg,
+	}
+}
+
+// Assess scores a login. failedAttempts is the number of wrong passwords
+// entered for the account just before this one. Signals that cannot be
+// checked, e.g. because Redis is down or GeoIP is not set up, add nothing.
+func (s *RiskService) Assess(user *models.UserModel, ip, ua string, failedAttempts int) RiskAssessment {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	signals := riskSignals{FailedAttempts: failedAttempts, LocalHour: -1}
+	loc := s.geo.Lookup(ip)
+
+	device := useragent.Parse(ua)
+	history, err := s.alertRepo.Known(ctx, user.ID, deviceFingerprint(device.Browser, device.OS, device.Device), loc.Country)
+	if err != nil {
+		log.Printf("risk: login history for user %d failed: %v", user.ID, err)
+	} else if !history.FirstLogin {
+		signals.NewDevice = history.NewDevice
+		signals.NewCountry = history.NewCountry
+	}
+
+	if s.cfg.MaxTravelSpeed > 0 && hasCoordinates(loc) {
+		last, err := s.alertRepo.GetLastLogin(ctx, user.ID)
+		if err != nil {
+			log.Printf("risk: last login for user %d failed: %v", user.ID, err)
+		} else if last != nil {
+			signals.Travelled = true
+			signals.TravelKm = haversineKm(last.Latitude, last.Longitude, loc.Latitude, loc.Longitude)
+			signals.TravelHours = now.Sub(last.At).Hours()
+			signals.PreviousCountry = last.Country
+		}
+	}
+
+	signals.Blocklisted = s.blocklist.Contains(ip)
+
+	// without a timezone there is no telling what is unusual for the user
+	if user.Timezone != "" {
+		if tz, err := time.LoadLocation(user.Timezone); err == nil {
+			signals.LocalHour = now.In(tz).Hour()
+		}
+	}
+
+	return scoreRisk(signals, s.cfg)
+}
+
+// riskSignals are what Assess found out about a login. scoreRisk turns them
+// into an assessment.
+type riskSignals struct {
+	NewDevice  bool
+	NewCountry bool
+	// Travelled is set when the previous login's location is known;
+	// TravelKm and TravelHours then measure the hop from it.
+	Travelled       bool
+	TravelKm        float64
+	TravelHours     float64
+	PreviousCountry string
+	Blocklisted     bool
+	FailedAttempts  int
+	// LocalHour is the hour in the user's timezone, or -1 if unknown.
+	LocalHour int
+}
+
+// scoreRisk adds up the signals and picks the decision from cfg's thresholds.
+func scoreRisk(signals riskSignals, cfg config.RiskConfig) RiskAssessment {
+	assessment := RiskAssessment{Details: map[string]any{}}
+
+	if signals.NewDevice {
+		assessment.add(RiskReasonNewDevice, riskScoreNewDevice)
+	}
+	if signals.NewCountry {
+		assessment.add(RiskReasonNewCountry, riskScoreNewCountry)
+	}
+
+	if cfg.MaxTravelSpeed > 0 && signals.Travelled && signals.TravelKm >= minTravelDistanceKm {
+		speed := math.Inf(1)
+		if signals.TravelHours > 0 {
+			speed = signals.TravelKm / signals.TravelHours
+		}
+		if speed > cfg.MaxTravelSpeed {
+			assessment.add(RiskReasonImpossibleTravel, riskScoreImpossibleTravel)
+			assessment.Details["distance_km"] = math.Round(signals.TravelKm)
+			assessment.Details["hours_since_last_login"] = math.Round(signals.TravelHours*100) / 100
+			assessment.Details["previous_country"] = signals.PreviousCountry
+		}
+	}
+
+	if signals.Blocklisted {
+		assessment.add(RiskReasonIPReputation, riskScoreIPReputation)
+	}
+
+	if signals.FailedAttempts > 0 {
+		// the account locks at maxLoginAttemts, so this stays bounded
+		assessment.add(RiskReasonFailedAttempts, signals.FailedAttempts*riskScorePerFailedAttempt)
+		assessment.Details["failed_attempts"] = signals.FailedAttempts
+	}
+
+	if signals.LocalHour >= unusualHourStart && signals.LocalHour < unusualHourEnd {
+		assessment.add(RiskReasonUnusualHour, riskScoreUnusualHour)
+		assessment.Details["local_hour"] = signals.LocalHour
+	}
+
+	switch {
+	case cfg.BlockThreshold > 0 && assessment.Score >= cfg.BlockThreshold:
+		assessment.Decision = RiskBlock
+	case cfg.MFAThreshold > 0 && assessment.Score >= cfg.MFAThreshold:
+		assessment.Decision = RiskStepUp
+	default:
+		assessment.Decision = RiskAllow
+	}
+
+	return assessment
+}
+
+// Log writes the assessment to the audit log.
+func (s *RiskService) Log(user *models.UserModel, ip, ua string, assessment RiskAssessment) {
+	metadata := map[string]any{
+		"score":    assessment.Score,
+		"reasons":  assessment.Reasons,
+		"decision": assessment.Decision,
+	}
+	for k, v := range assessment.Details {
+		metadata[k] = v
+	}
+
+	s.auditRepo.LogWithMetadata("LOGIN_RISK_ASSESSED", &user.ID, ip, ua, metadata)
+}
+
+// RecordLogin remembers where a successful login came from, for the travel
+// check on the next one.
+func (s *RiskService) RecordLogin(userID uint, ip string) {
+	loc := s.geo.Lookup(ip)
+	if !hasCoordinates(loc) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.alertRepo.SetLastLogin(ctx, userID, repositories.LastLogin{
+		At:        time.Now(),
+		Country:   loc.Country,
+		Latitude:  loc.Latitude,
+		Longitude: loc.Longitude,
+	}, loginHistoryTTL)
+	if err != nil {
+		log.Printf("risk: saving last login for user %d failed: %v", userID, err)
+	}
+}
+
+// hasCoordinates treats 0,0 as unknown; nobody logs in from there.
+func hasCoordinates(loc geoip.Location) bool {
+	return loc.Latitude != 0 || loc.Longitude != 0
+}
+
+// haversineKm is the great-circle distance between two points.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(lat2 - lat1)
+	dLon := rad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
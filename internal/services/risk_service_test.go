@@ -0,0 +1,267 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+)
+
+// defaultRiskConfig matches the RISK_* defaults in config.Load.
+var defaultRiskConfig = config.RiskConfig{MFAThreshold: 50, BlockThreshold: 90, MaxTravelSpeed: 1000}
+
+func TestScoreRiskSignals(t *testing.T) {
+	quiet := riskSignals{LocalHour: 12}
+
+	tests := []struct {
+		name         string
+		signals      func(s *riskSignals)
+		wantScore    int
+		wantReasons  []string
+		wantDecision string
+	}{
+		{
+			name:         "nothing unusual",
+			signals:      func(s *riskSignals) {},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "new device",
+			signals:      func(s *riskSignals) { s.NewDevice = true },
+			wantScore:    20,
+			wantReasons:  []string{RiskReasonNewDevice},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "new country",
+			signals:      func(s *riskSignals) { s.NewCountry = true },
+			wantScore:    20,
+			wantReasons:  []string{RiskReasonNewCountry},
+			wantDecision: RiskAllow,
+		},
+		{
+			name: "impossible travel",
+			signals: func(s *riskSignals) {
+				s.Travelled, s.TravelKm, s.TravelHours = true, 6000, 1
+			},
+			wantScore:    50,
+			wantReasons:  []string{RiskReasonImpossibleTravel},
+			wantDecision: RiskStepUp,
+		},
+		{
+			name: "instant hop counts as travel",
+			signals: func(s *riskSignals) {
+				s.Travelled, s.TravelKm, s.TravelHours = true, 600, 0
+			},
+			wantScore:    50,
+			wantReasons:  []string{RiskReasonImpossibleTravel},
+			wantDecision: RiskStepUp,
+		},
+		{
+			name: "possible travel",
+			signals: func(s *riskSignals) {
+				s.Travelled, s.TravelKm, s.TravelHours = true, 6000, 10
+			},
+			wantDecision: RiskAllow,
+		},
+		{
+			name: "short hop is not travel",
+			signals: func(s *riskSignals) {
+				s.Travelled, s.TravelKm, s.TravelHours = true, 499, 0.01
+			},
+			wantDecision: RiskAllow,
+		},
+		{
+			name: "no previous location",
+			signals: func(s *riskSignals) {
+				s.TravelKm, s.TravelHours = 6000, 1
+			},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "blocklisted ip",
+			signals:      func(s *riskSignals) { s.Blocklisted = true },
+			wantScore:    60,
+			wantReasons:  []string{RiskReasonIPReputation},
+			wantDecision: RiskStepUp,
+		},
+		{
+			name:         "one failed attempt",
+			signals:      func(s *riskSignals) { s.FailedAttempts = 1 },
+			wantScore:    10,
+			wantReasons:  []string{RiskReasonFailedAttempts},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "five failed attempts",
+			signals:      func(s *riskSignals) { s.FailedAttempts = 5 },
+			wantScore:    50,
+			wantReasons:  []string{RiskReasonFailedAttempts},
+			wantDecision: RiskStepUp,
+		},
+		{
+			name:         "midnight",
+			signals:      func(s *riskSignals) { s.LocalHour = 0 },
+			wantScore:    10,
+			wantReasons:  []string{RiskReasonUnusualHour},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "four in the morning",
+			signals:      func(s *riskSignals) { s.LocalHour = 4 },
+			wantScore:    10,
+			wantReasons:  []string{RiskReasonUnusualHour},
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "five in the morning",
+			signals:      func(s *riskSignals) { s.LocalHour = 5 },
+			wantDecision: RiskAllow,
+		},
+		{
+			name:         "unknown timezone",
+			signals:      func(s *riskSignals) { s.LocalHour = -1 },
+			wantDecision: RiskAllow,
+		},
+		{
+			name: "new device in a new country",
+			signals: func(s *riskSignals) {
+				s.NewDevice, s.NewCountry = true, true
+			},
+			wantScore:    40,
+			wantReasons:  []string{RiskReasonNewDevice, RiskReasonNewCountry},
+			wantDecision: RiskAllow,
+		},
+		{
+			name: "new device in a new country at night",
+			signals: func(s *riskSignals) {
+				s.NewDevice, s.NewCountry, s.LocalHour = true, true, 3
+			},
+			wantScore:    50,
+			wantReasons:  []string{RiskReasonNewDevice, RiskReasonNewCountry, RiskReasonUnusualHour},
+			wantDecision: RiskStepUp,
+		},
+		{
+			name: "blocklisted ip after failed attempts",
+			signals: func(s *riskSignals) {
+				s.Blocklisted, s.FailedAttempts = true, 3
+			},
+			wantScore:    90,
+			wantReasons:  []string{RiskReasonIPReputation, RiskReasonFailedAttempts},
+			wantDecision: RiskBlock,
+		},
+		{
+			name: "impossible travel to a new country from a blocklisted ip",
+			signals: func(s *riskSignals) {
+				s.NewCountry, s.Blocklisted = true, true
+				s.Travelled, s.TravelKm, s.TravelHours = true, 6000, 1
+			},
+			wantScore:    130,
+			wantReasons:  []string{RiskReasonNewCountry, RiskReasonImpossibleTravel, RiskReasonIPReputation},
+			wantDecision: RiskBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signals := quiet
+			tt.signals(&signals)
+
+			got := scoreRisk(signals, defaultRiskConfig)
+			if got.Score != tt.wantScore {
+				t.Errorf("score = %d, want %d", got.Score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(got.Reasons, tt.wantReasons) {
+				t.Errorf("reasons = %v, want %v", got.Reasons, tt.wantReasons)
+			}
+			if got.Decision != tt.wantDecision {
+				t.Errorf("decision = %q, want %q", got.Decision, tt.wantDecision)
+			}
+		})
+	}
+}
+
+func TestScoreRiskThresholds(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.RiskConfig
+		score int
+		want  string
+	}{
+		{name: "below step-up", cfg: defaultRiskConfig, score: 40, want: RiskAllow},
+		{name: "at step-up", cfg: defaultRiskConfig, score: 50, want: RiskStepUp},
+		{name: "below block", cfg: defaultRiskConfig, score: 80, want: RiskStepUp},
+		{name: "at block", cfg: defaultRiskConfig, score: 90, want: RiskBlock},
+		{name: "step-up disabled", cfg: config.RiskConfig{BlockThreshold: 90}, score: 80, want: RiskAllow},
+		{name: "step-up disabled still blocks", cfg: config.RiskConfig{BlockThreshold: 90}, score: 90, want: RiskBlock},
+		{name: "block disabled", cfg: config.RiskConfig{MFAThreshold: 50}, score: 200, want: RiskStepUp},
+		{name: "both disabled", cfg: config.RiskConfig{}, score: 200, want: RiskAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// failed attempts score 10 each, so they can hit any multiple of 10
+			signals := riskSignals{FailedAttempts: tt.score / riskScorePerFailedAttempt, LocalHour: -1}
+
+			got := scoreRisk(signals, tt.cfg)
+			if got.Score != tt.score {
+				t.Fatalf("score = %d, want %d", got.Score, tt.score)
+			}
+			if got.Decision != tt.want {
+				t.Errorf("decision at %d = %q, want %q", tt.score, got.Decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreRiskTravelNeedsSpeedLimit(t *testing.T) {
+	cfg := defaultRiskConfig
+	cfg.MaxTravelSpeed = 0
+
+	got := scoreRisk(riskSignals{Travelled: true, TravelKm: 6000, TravelHours: 1, LocalHour: -1}, cfg)
+	if got.Score != 0 {
+		t.Errorf("score with the travel check off = %d, want 0", got.Score)
+	}
+}
+
+func TestScoreRiskTravelDetails(t *testing.T) {
+	got := scoreRisk(riskSignals{
+		Travelled:       true,
+		TravelKm:        6012.4,
+		TravelHours:     1.234,
+		PreviousCountry: "DE",
+		LocalHour:       -1,
+	}, defaultRiskConfig)
+
+	want := map[string]any{
+		"distance_km":            6012.0,
+		"hours_since_last_login": 1.23,
+		"previous_country":       "DE",
+	}
+	if !reflect.DeepEqual(got.Details, want) {
+		t.Errorf("details = %v, want %v", got.Details, want)
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{name: "same place", lat1: 52.52, lon1: 13.405, lat2: 52.52, lon2: 13.405, want: 0},
+		{name: "berlin to paris", lat1: 52.52, lon1: 13.405, lat2: 48.8566, lon2: 2.3522, want: 878},
+		{name: "london to new york", lat1: 51.5074, lon1: -0.1278, lat2: 40.7128, lon2: -74.006, want: 5570},
+		{name: "across the antimeridian", lat1: 0, lon1: 179.5, lat2: 0, lon2: -179.5, want: 111},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversineKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 5 {
+				t.Errorf("haversineKm = %.1f, want about %.0f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scimComparison is one "attrPath op value" term of a SCIM filter.
+type scimComparison struct {
+	Attr  string
+	Op    string
+	Value string
+}
+
+var scimOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "pr": true,
+}
+
+// tokenizeSCIMFilter splits a filter into words and quoted strings. Quoted
+// strings keep their quotes so they can be told apart from keywords.
+func tokenizeSCIMFilter(filter string) ([]string, error) {
+	var tokens []string
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"':
+			var b strings.Builder
+			b.WriteRune('"')
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					b.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, scimInvalidFilter("unterminated string")
+			}
+			tokens = append(tokens, b.String())
+		case runes[i] == '(' || runes[i] == ')' || runes[i] == '[' || runes[i] == ']':
+			return nil, scimInvalidFilter("grouping and value paths are not supported")
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseSCIMFilter parses the subset of RFC 7644 filters we support: one or
+// more comparisons joined by "and". "or", "not" and grouping are rejected
+// with invalidFilter rather than silently ignored.
+func parseSCIMFilter(filter string) ([]scimComparison, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var comparisons []scimComparison
+	for i := 0; i < len(tokens); {
+		if len(comparisons) > 0 {
+			if !strings.EqualFold(tokens[i], "and") {
+				return nil, scimInvalidFilter("only \"and\" is supported between comparisons")
+			}
+			i++
+		}
+
+		if i+1 >= len(tokens) {
+			return nil, scimInvalidFilter("incomplete comparison")
+		}
+
+		cmp := scimComparison{
+			Attr: strings.ToLower(tokens[i]),
+			Op:   strings.ToLower(tokens[i+1]),
+		}
+		if !scimOperators[cmp.Op] {
+			return nil, scimInvalidFilter("unsupported operator " + tokens[i+1])
+		}
+		i += 2
+
+		if cmp.Op != "pr" {
+			if i >= len(tokens) {
+				return nil, scimInvalidFilter("missing comparison value")
+			}
+			// strings carry their opening quote; true/false/numbers do not
+			cmp.Value = strings.TrimPrefix(tokens[i], `"`)
+			i++
+		}
+
+		comparisons = append(comparisons, cmp)
+	}
+
+	return comparisons, nil
+}
+
+// matchSCIM evaluates a comparison against an in-memory string value, used
+// for resources that are not backed by a table (groups).
+func matchSCIM(cmp scimComparison, value string) bool {
+	value = strings.ToLower(value)
+	want := strings.ToLower(cmp.Value)
+
+	switch cmp.Op {
+	case "eq":
+		return value == want
+	case "ne":
+		return value != want
+	case "co":
+		return strings.Contains(value, want)
+	case "sw":
+		return strings.HasPrefix(value, want)
+	case "ew":
+		return strings.HasSuffix(value, want)
+	case "pr":
+		return value != ""
+	}
+	return false
+}
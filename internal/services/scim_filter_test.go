@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   []scimComparison
+	}{
+		{name: "empty", filter: "", want: nil},
+		{name: "blank", filter: "   ", want: nil},
+		{
+			name:   "string equality",
+			filter: `userName eq "alice@example.com"`,
+			want:   []scimComparison{{Attr: "username", Op: "eq", Value: "alice@example.com"}},
+		},
+		{
+			name:   "keywords are case insensitive",
+			filter: `UserName EQ "Alice@Example.com"`,
+			want:   []scimComparison{{Attr: "username", Op: "eq", Value: "Alice@Example.com"}},
+		},
+		{
+			name:   "boolean value",
+			filter: `active eq true`,
+			want:   []scimComparison{{Attr: "active", Op: "eq", Value: "true"}},
+		},
+		{
+			name:   "present takes no value",
+			filter: `externalId pr`,
+			want:   []scimComparison{{Attr: "externalid", Op: "pr"}},
+		},
+		{
+			name:   "joined with and",
+			filter: `userName sw "a" and active eq false AND externalId pr`,
+			want: []scimComparison{
+				{Attr: "username", Op: "sw", Value: "a"},
+				{Attr: "active", Op: "eq", Value: "false"},
+				{Attr: "externalid", Op: "pr"},
+			},
+		},
+		{
+			name:   "quoted keywords are values",
+			filter: `userName eq "and"`,
+			want:   []scimComparison{{Attr: "username", Op: "eq", Value: "and"}},
+		},
+		{
+			name:   "escaped quote",
+			filter: `externalId eq "a\"b"`,
+			want:   []scimComparison{{Attr: "externalid", Op: "eq", Value: `a"b`}},
+		},
+		{
+			name:   "spaces inside a string",
+			filter: `externalId co "two words"`,
+			want:   []scimComparison{{Attr: "externalid", Op: "co", Value: "two words"}},
+		},
+		{
+			name:   "non-ascii",
+			filter: `userName ew "ü.example"`,
+			want:   []scimComparison{{Attr: "username", Op: "ew", Value: "ü.example"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSCIMFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseSCIMFilter(%q): %v", tt.filter, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSCIMFilter(%q)\n got  %+v\n want %+v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilterRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{name: "attribute only", filter: `userName`},
+		{name: "missing value", filter: `userName eq`},
+		{name: "unknown operator", filter: `userName gt "a"`},
+		{name: "or", filter: `userName eq "a" or userName eq "b"`},
+		{name: "dangling and", filter: `userName eq "a" and`},
+		{name: "two comparisons without and", filter: `userName eq "a" active eq true`},
+		{name: "trailing token", filter: `userName eq "a"extra`},
+		{name: "not", filter: `not (userName eq "a")`},
+		{name: "grouping", filter: `(userName eq "a")`},
+		{name: "value path", filter: `emails[type eq "work"]`},
+		{name: "unterminated string", filter: `userName eq "alice`},
+		{name: "escape at end of string", filter: `userName eq "alice\"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSCIMFilter(tt.filter)
+			if err == nil {
+				t.Fatalf("parseSCIMFilter(%q) = %+v, want an error", tt.filter, got)
+			}
+			assertInvalidFilter(t, err)
+		})
+	}
+}
+
+func TestUserConditions(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   []repositories.SCIMCondition
+	}{
+		{
+			name:   "userName is the lower cased email",
+			filter: `userName eq "Alice@Example.com"`,
+			want:   []repositories.SCIMCondition{{Field: "email", Op: "eq", Value: "alice@example.com"}},
+		},
+		{
+			name:   "emails value",
+			filter: `emails.value co "example"`,
+			want:   []repositories.SCIMCondition{{Field: "email", Op: "co", Value: "example"}},
+		},
+		{
+			name:   "externalId keeps its case",
+			filter: `externalId eq "ABC"`,
+			want:   []repositories.SCIMCondition{{Field: "external_id", Op: "eq", Value: "ABC"}},
+		},
+		{
+			name:   "active is normalised",
+			filter: `active ne "TRUE"`,
+			want:   []repositories.SCIMCondition{{Field: "active", Op: "ne", Value: "true"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := userConditions(tt.filter)
+			if err != nil {
+				t.Fatalf("userConditions(%q): %v", tt.filter, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("userConditions(%q)\n got  %+v\n want %+v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserConditionsRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{name: "unknown attribute", filter: `name.givenName eq "Alice"`},
+		{name: "active with co", filter: `active co "t"`},
+		{name: "active not a boolean", filter: `active eq "yes"`},
+		{name: "bad grammar", filter: `userName eq "a" or active eq true`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := userConditions(tt.filter); err == nil {
+				t.Fatalf("userConditions(%q) succeeded, want an error", tt.filter)
+			} else {
+				assertInvalidFilter(t, err)
+			}
+		})
+	}
+}
+
+func TestMatchSCIM(t *testing.T) {
+	tests := []struct {
+		op, want, value string
+		match           bool
+	}{
+		{op: "eq", want: "Admin", value: "admin", match: true},
+		{op: "eq", want: "admin", value: "owner", match: false},
+		{op: "ne", want: "admin", value: "owner", match: true},
+		{op: "ne", want: "admin", value: "ADMIN", match: false},
+		{op: "co", want: "wn", value: "owner", match: true},
+		{op: "co", want: "x", value: "owner", match: false},
+		{op: "sw", want: "ow", value: "owner", match: true},
+		{op: "sw", want: "ne", value: "owner", match: false},
+		{op: "ew", want: "ER", value: "owner", match: true},
+		{op: "ew", want: "ow", value: "owner", match: false},
+		{op: "pr", value: "owner", match: true},
+		{op: "pr", value: "", match: false},
+		{op: "gt", want: "a", value: "b", match: false},
+	}
+
+	for _, tt := range tests {
+		cmp := scimComparison{Attr: "displayname", Op: tt.op, Value: tt.want}
+		if got := matchSCIM(cmp, tt.value); got != tt.match {
+			t.Errorf("matchSCIM(%s %q, %q) = %v, want %v", tt.op, tt.want, tt.value, got, tt.match)
+		}
+	}
+}
+
+func assertInvalidFilter(t *testing.T, err error) {
+	t.Helper()
+
+	var scimErr *SCIMError
+	if !errors.As(err, &scimErr) {
+		t.Fatalf("error %v is not a *SCIMError", err)
+	}
+	if scimErr.Status != 400 || scimErr.ScimType != "invalidFilter" {
+		t.Errorf("error = %d %s (%s), want 400 invalidFilter", scimErr.Status, scimErr.ScimType, scimErr.Detail)
+	}
+}
@@ -0,0 +1,1079 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	SCIMSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	SCIMMaxResults   = 200
+	scimDefaultCount = 100
+)
+
+// SCIMError is returned by SCIMService for failures that map onto a SCIM
+// error response (RFC 7644 section 3.12).
+type SCIMError struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
+
+func scimInvalidFilter(detail string) error {
+	return &SCIMError{Status: 400, ScimType: "invalidFilter", Detail: detail}
+}
+
+func scimInvalidValue(detail string) error {
+	return &SCIMError{Status: 400, ScimType: "invalidValue", Detail: detail}
+}
+
+var (
+	errSCIMNotFound   = &SCIMError{Status: 404, Detail: "resource not found"}
+	errSCIMUserExists = &SCIMError{Status: 409, ScimType: "uniqueness", Detail: "user already exists"}
+	errSCIMLastOwner  = &SCIMError{Status: 400, ScimType: "mutability", Detail: "organization must keep at least one owner"}
+	// accounts that signed up or were invited belong to their users, not
+	// to the organization's identity provider
+	errSCIMAccountExists = &SCIMError{Status: 409, ScimType: "uniqueness", Detail: "an account with this userName already exists; invite it to the organization instead"}
+	errSCIMNotManaged    = &SCIMError{Status: 400, ScimType: "mutability", Detail: "userName and active can only be changed for users provisioned by this organization"}
+)
+
+/* ============================
+   Resources
+============================ */
+
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type SCIMUserResource struct {
+	Schemas    []string     `json:"schemas"`
+	ID         string       `json:"id"`
+	ExternalID string       `json:"externalId,omitempty"`
+	UserName   string       `json:"userName"`
+	Active     bool         `json:"active"`
+	Emails     []SCIMEmail  `json:"emails"`
+	Groups     []SCIMMember `json:"groups"`
+	Meta       SCIMMeta     `json:"meta"`
+}
+
+type SCIMGroupResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        SCIMMeta     `json:"meta"`
+}
+
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// scimBool accepts both JSON booleans and the "True"/"False" strings some
+// identity providers send.
+type scimBool bool
+
+func (b *scimBool) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err == nil {
+		*b = scimBool(v)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return err
+	}
+	*b = scimBool(v)
+	return nil
+}
+
+type SCIMUserInput struct {
+	UserName   string      `json:"userName"`
+	ExternalID string      `json:"externalId"`
+	Active     *scimBool   `json:"active"`
+	Emails     []SCIMEmail `json:"emails"`
+	Password   string      `json:"password"`
+}
+
+type SCIMGroupInput struct {
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members"`
+}
+
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+/* ============================
+   Service
+============================ */
+
+// SCIMService provisions users into one organization per SCIM token. Users
+// map onto UserModel plus their membership; groups are the fixed
+// organization roles, so group membership is role assignment.
+type SCIMService struct {
+	scimRepo    *repositories.SCIMRepository
+	userRepo    *repositories.UserRepository
+	orgRepo     *repositories.OrganizationRepository
+	sessionRepo *repositories.SessionRepository
+	auditRepo   *repositories.AuditRepo
+}
+
+func NewSCIMService(scimRepo *repositories.SCIMRepository, userRepo *repositories.UserRepository, orgRepo *repositories.OrganizationRepository, sessionRepo *repositories.SessionRepository, auditRepo *repositories.AuditRepo) *SCIMService {
+	return &SCIMService{
+		scimRepo:    scimRepo,
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+func (s *SCIMService) audit(event string, tokenID, orgID uint, userID *uint, ip, ua string, extra map[string]any) {
+	metadata := map[string]any{
+		"scim_token_id": tokenID,
+		"org_id":        orgID,
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	s.auditRepo.LogWithMetadata(event, userID, ip, ua, metadata)
+}
+
+/* ============================
+   Tokens
+============================ */
+
+// VerifyToken resolves a bearer token to the organization it provisions.
+func (s *SCIMService) VerifyToken(rowToken string) (uint, uint, error) {
+	token, err := s.scimRepo.FindActiveToken(rowToken)
+	if err != nil {
+		return 0, 0, err
+	}
+	if token == nil {
+		return 0, 0, ErrInvalidCredentials
+	}
+
+	s.scimRepo.TouchToken(token.ID)
+	return token.OrganizationID, token.ID, nil
+}
+
+// CreateToken issues a SCIM bearer token for the organization. The token is
+// returned once; only its hash is stored.
+func (s *SCIMService) CreateToken(actorID, orgID uint, name, ip, ua string) (*models.SCIMToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidInput
+	}
+
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return nil, "", err
+	}
+	if org == nil {
+		return nil, "", ErrNotFound
+	}
+
+	tokenByte := make([]byte, 32)
+	if _, err := rand.Read(tokenByte); err != nil {
+		return nil, "", err
+	}
+	rowToken := "scim_" + base64.RawURLEncoding.EncodeToString(tokenByte)
+
+	token := &models.SCIMToken{
+		OrganizationID: orgID,
+		Name:           name,
+		CreatedBy:      &actorID,
+	}
+	if err := s.scimRepo.CreateToken(token, rowToken); err != nil {
+		return nil, "", err
+	}
+
+	s.auditRepo.LogWithMetadata("SCIM_TOKEN_CREATED", &actorID, ip, ua, map[string]any{
+		"scim_token_id": token.ID,
+		"org_id":        orgID,
+	})
+
+	return token, rowToken, nil
+}
+
+func (s *SCIMService) ListTokens(orgID uint) ([]models.SCIMToken, error) {
+	return s.scimRepo.ListTokens(orgID)
+}
+
+func (s *SCIMService) RevokeToken(actorID, orgID, tokenID uint, ip, ua string) error {
+	revoked, err := s.scimRepo.RevokeToken(orgID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNotFound
+	}
+
+	s.auditRepo.LogWithMetadata("SCIM_TOKEN_REVOKED", &actorID, ip, ua, map[string]any{
+		"scim_token_id": tokenID,
+		"org_id":        orgID,
+	})
+
+	return nil
+}
+
+/* ============================
+   Helpers
+============================ */
+
+// scimPage converts SCIM's 1-based startIndex and count into offset/limit.
+func scimPage(startIndex, count int) (int, int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = scimDefaultCount
+	}
+	if count > SCIMMaxResults {
+		count = SCIMMaxResults
+	}
+	return startIndex - 1, count, startIndex
+}
+
+func scimID(id string) (uint, bool) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	return uint(n), true
+}
+
+func normaliseUserName(userName string, emails []SCIMEmail) (string, error) {
+	email := strings.TrimSpace(userName)
+	if email == "" {
+		for _, e := range emails {
+			if e.Primary || email == "" {
+				email = strings.TrimSpace(e.Value)
+			}
+		}
+	}
+
+	email = strings.ToLower(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", scimInvalidValue("userName must be an email address")
+	}
+	return email, nil
+}
+
+// revokeOrgAccess signs the user out of sessions acting in the
+// organization. Sessions in other organizations are not this
+// organization's to end.
+func (s *SCIMService) revokeOrgAccess(orgID, userID uint) error {
+	return s.sessionRepo.DeleteByOrg(context.Background(), userID, orgID)
+}
+
+// managedBy reports whether the organization's SCIM client created the
+// account and so may change it.
+func managedBy(u *repositories.SCIMUser, orgID uint) bool {
+	return u.ManagedByOrgID != nil && *u.ManagedByOrgID == orgID
+}
+
+func userLocation(id uint) string {
+	return "/scim/v2/Users/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func groupLocation(role models.OrgRole) string {
+	return "/scim/v2/Groups/" + string(role)
+}
+
+func toSCIMUser(u *repositories.SCIMUser) *SCIMUserResource {
+	created, modified := u.CreatedAt, u.UpdatedAt
+	res := &SCIMUserResource{
+		Schemas:  []string{SCIMSchemaUser},
+		ID:       strconv.FormatUint(uint64(u.ID), 10),
+		UserName: u.Email,
+		Active:   u.SuspendedAt == nil,
+		Emails:   []SCIMEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Groups: []SCIMMember{{
+			Value:   string(u.OrgRole),
+			Display: string(u.OrgRole),
+			Ref:     groupLocation(u.OrgRole),
+		}},
+		Meta: SCIMMeta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     userLocation(u.ID),
+		},
+	}
+	if u.ExternalID != nil {
+		res.ExternalID = *u.ExternalID
+	}
+	return res
+}
+
+/* ============================
+   Users
+============================ */
+
+// userConditions maps a parsed filter onto repository conditions.
+func userConditions(filter string) ([]repositories.SCIMCondition, error) {
+	comparisons, err := parseSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conds := make([]repositories.SCIMCondition, 0, len(comparisons))
+	for _, cmp := range comparisons {
+		cond := repositories.SCIMCondition{Op: cmp.Op, Value: cmp.Value}
+
+		switch cmp.Attr {
+		case "username", "emails", "emails.value":
+			cond.Field = "email"
+			cond.Value = strings.ToLower(cmp.Value)
+		case "externalid":
+			cond.Field = "external_id"
+		case "id":
+			cond.Field = "id"
+		case "active":
+			if cmp.Op != "eq" && cmp.Op != "ne" {
+				return nil, scimInvalidFilter("active only supports eq and ne")
+			}
+			active, err := strconv.ParseBool(cmp.Value)
+			if err != nil {
+				return nil, scimInvalidFilter("active must be compared to true or false")
+			}
+			cond.Field = "active"
+			cond.Value = strconv.FormatBool(active)
+		default:
+			return nil, scimInvalidFilter("unsupported filter attribute " + cmp.Attr)
+		}
+
+		conds = append(conds, cond)
+	}
+
+	return conds, nil
+}
+
+func (s *SCIMService) ListUsers(orgID uint, filter string, startIndex, count int) (*SCIMListResponse, error) {
+	conds, err := userConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, limit, start := scimPage(startIndex, count)
+
+	users, total, err := s.scimRepo.ListUsers(orgID, conds, offset, limit)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidFilter) {
+			return nil, scimInvalidFilter("unsupported filter")
+		}
+		return nil, err
+	}
+
+	resources := make([]any, 0, len(users))
+	for i := range users {
+		resources = append(resources, toSCIMUser(&users[i]))
+	}
+
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *SCIMService) findUser(orgID uint, id string) (*repositories.SCIMUser, error) {
+	userID, ok := scimID(id)
+	if !ok {
+		return nil, errSCIMNotFound
+	}
+
+	user, err := s.scimRepo.FindUser(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errSCIMNotFound
+	}
+	return user, nil
+}
+
+func (s *SCIMService) GetUser(orgID uint, id string) (*SCIMUserResource, error) {
+	user, err := s.findUser(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMUser(user), nil
+}
+
+// CreateUser provisions a new account managed by the organization. An
+// existing account with the same email is never linked: it may belong to
+// another organization or nobody's, so the conflict is reported instead.
+func (s *SCIMService) CreateUser(orgID, tokenID uint, in SCIMUserInput, ip, ua string) (*SCIMUserResource, error) {
+	email, err := normaliseUserName(in.UserName, in.Emails)
+	if err != nil {
+		return nil, err
+	}
+
+	var externalID *string
+	if ext := strings.TrimSpace(in.ExternalID); ext != "" {
+		taken, err := s.scimRepo.ExternalIDTaken(orgID, 0, ext)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, &SCIMError{Status: 409, ScimType: "uniqueness", Detail: "externalId already in use"}
+		}
+		externalID = &ext
+	}
+
+	existing, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		membership, err := s.orgRepo.GetMembership(orgID, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if membership != nil {
+			return nil, errSCIMUserExists
+		}
+		return nil, errSCIMAccountExists
+	}
+
+	user := &models.UserModel{
+		Email:    email,
+		Role:     models.User,
+		Status:   models.StatusActive,
+		Password: unusablePassword,
+	}
+	if in.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = string(hash)
+	}
+
+	if err := s.scimRepo.CreateMember(orgID, user, externalID); err != nil {
+		return nil, err
+	}
+
+	if in.Active != nil && !bool(*in.Active) {
+		now := time.Now()
+		if err := s.scimRepo.SetSuspended(orgID, user.ID, &now); err != nil {
+			return nil, err
+		}
+	}
+
+	s.audit("SCIM_USER_CREATED", tokenID, orgID, &user.ID, ip, ua, nil)
+
+	created, err := s.scimRepo.FindUser(orgID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMUser(created), nil
+}
+
+type scimUserChanges struct {
+	userName   *string
+	externalID *string // "" clears it
+	active     *bool
+}
+
+// applyUserChanges changes userName and active only on accounts the
+// organization manages; active suspends the membership, never the account,
+// which may be used in other organizations.
+func (s *SCIMService) applyUserChanges(orgID, tokenID uint, current *repositories.SCIMUser, changes scimUserChanges, ip, ua string) error {
+	changed := map[string]any{}
+
+	if changes.userName != nil {
+		email, err := normaliseUserName(*changes.userName, nil)
+		if err != nil {
+			return err
+		}
+
+		if email != current.Email {
+			if !managedBy(current, orgID) {
+				return errSCIMNotManaged
+			}
+
+			other, err := s.userRepo.FindByEmail(email)
+			if err != nil {
+				return err
+			}
+			if other != nil {
+				return errSCIMUserExists
+			}
+			if err := s.scimRepo.UpdateEmail(current.ID, email); err != nil {
+				return err
+			}
+			changed["userName"] = email
+		}
+	}
+
+	if changes.externalID != nil {
+		ext := strings.TrimSpace(*changes.externalID)
+		currentExt := ""
+		if current.ExternalID != nil {
+			currentExt = *current.ExternalID
+		}
+
+		if ext != currentExt {
+			var value *string
+			if ext != "" {
+				taken, err := s.scimRepo.ExternalIDTaken(orgID, current.ID, ext)
+				if err != nil {
+					return err
+				}
+				if taken {
+					return &SCIMError{Status: 409, ScimType: "uniqueness", Detail: "externalId already in use"}
+				}
+				value = &ext
+			}
+			if err := s.scimRepo.SetExternalID(orgID, current.ID, value); err != nil {
+				return err
+			}
+			changed["externalId"] = ext
+		}
+	}
+
+	if changes.active != nil {
+		isActive := current.SuspendedAt == nil
+		if *changes.active != isActive {
+			if !managedBy(current, orgID) {
+				return errSCIMNotManaged
+			}
+
+			var suspendedAt *time.Time
+			if !*changes.active {
+				if current.OrgRole == models.OrgOwner {
+					owners, err := s.orgRepo.CountOwners(orgID)
+					if err != nil {
+						return err
+					}
+					if owners <= 1 {
+						return errSCIMLastOwner
+					}
+				}
+				now := time.Now()
+				suspendedAt = &now
+			}
+			if err := s.scimRepo.SetSuspended(orgID, current.ID, suspendedAt); err != nil {
+				return err
+			}
+			if suspendedAt != nil {
+				if err := s.revokeOrgAccess(orgID, current.ID); err != nil {
+					return err
+				}
+			}
+			changed["active"] = *changes.active
+		}
+	}
+
+	if len(changed) > 0 {
+		s.audit("SCIM_USER_UPDATED", tokenID, orgID, &current.ID, ip, ua, changed)
+	}
+
+	return nil
+}
+
+// ReplaceUser implements PUT: attributes missing from the request are reset
+// (externalId cleared, active defaults to true).
+func (s *SCIMService) ReplaceUser(orgID, tokenID uint, id string, in SCIMUserInput, ip, ua string) (*SCIMUserResource, error) {
+	current, err := s.findUser(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := normaliseUserName(in.UserName, in.Emails)
+	if err != nil {
+		return nil, err
+	}
+
+	active := true
+	if in.Active != nil {
+		active = bool(*in.Active)
+	}
+
+	changes := scimUserChanges{
+		userName:   &email,
+		externalID: &in.ExternalID,
+		active:     &active,
+	}
+	if err := s.applyUserChanges(orgID, tokenID, current, changes, ip, ua); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(orgID, id)
+}
+
+// userPatchValue applies one attribute of a PATCH to changes. Attributes we
+// do not store (name, displayName, ...) are accepted and ignored so
+// identity providers that always send them keep working.
+func userPatchValue(changes *scimUserChanges, op, attr string, value json.RawMessage) error {
+	switch {
+	case attr == "active":
+		if op == "remove" {
+			return nil
+		}
+		var active scimBool
+		if err := json.Unmarshal(value, &active); err != nil {
+			return scimInvalidValue("active must be a boolean")
+		}
+		v := bool(active)
+		changes.active = &v
+
+	case attr == "externalid":
+		ext := ""
+		if op != "remove" {
+			if err := json.Unmarshal(value, &ext); err != nil {
+				return scimInvalidValue("externalId must be a string")
+			}
+		}
+		changes.externalID = &ext
+
+	case attr == "username" || strings.HasPrefix(attr, "emails"):
+		if op == "remove" {
+			return &SCIMError{Status: 400, ScimType: "mutability", Detail: "userName is required"}
+		}
+
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			var emails []SCIMEmail
+			if err := json.Unmarshal(value, &emails); err != nil {
+				return scimInvalidValue(attr + " must be a string or a list of emails")
+			}
+			if email, err = normaliseUserName("", emails); err != nil {
+				return err
+			}
+		}
+		changes.userName = &email
+	}
+
+	return nil
+}
+
+func (s *SCIMService) PatchUser(orgID, tokenID uint, id string, req SCIMPatchRequest, ip, ua string) (*SCIMUserResource, error) {
+	current, err := s.findUser(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Operations) == 0 {
+		return nil, scimInvalidValue("Operations is required")
+	}
+
+	var changes scimUserChanges
+	for _, operation := range req.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return nil, scimInvalidValue("unsupported op " + operation.Op)
+		}
+
+		path := strings.ToLower(strings.TrimSpace(operation.Path))
+		if path != "" {
+			if err := userPatchValue(&changes, op, path, operation.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// without a path the value is an object of attributes to set
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attrs); err != nil {
+			return nil, &SCIMError{Status: 400, ScimType: "noTarget", Detail: "value must be an object when path is omitted"}
+		}
+		for attr, value := range attrs {
+			if err := userPatchValue(&changes, op, strings.ToLower(attr), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.applyUserChanges(orgID, tokenID, current, changes, ip, ua); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(orgID, id)
+}
+
+// DeleteUser deprovisions the user from the organization. The account
+// itself is kept since it may belong to other organizations; only its
+// sessions in this organization are revoked.
+func (s *SCIMService) DeleteUser(orgID, tokenID uint, id string, ip, ua string) error {
+	user, err := s.findUser(orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if user.OrgRole == models.OrgOwner {
+		owners, err := s.orgRepo.CountOwners(orgID)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return errSCIMLastOwner
+		}
+	}
+
+	if err := s.orgRepo.RemoveMember(orgID, user.ID); err != nil {
+		return err
+	}
+	if err := s.revokeOrgAccess(orgID, user.ID); err != nil {
+		return err
+	}
+
+	s.audit("SCIM_USER_DEPROVISIONED", tokenID, orgID, &user.ID, ip, ua, nil)
+
+	return nil
+}
+
+/* ============================
+   Groups
+============================ */
+
+var scimGroups = []models.OrgRole{models.OrgOwner, models.OrgAdmin, models.OrgMember}
+
+// memberValuePath matches `members[value eq "42"]` in PATCH remove paths.
+var memberValuePath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+func (s *SCIMService) groupResources(orgID uint, withMembers bool) ([]SCIMGroupResource, error) {
+	members, err := s.orgRepo.ListMembers(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]SCIMGroupResource, 0, len(scimGroups))
+	for _, role := range scimGroups {
+		group := SCIMGroupResource{
+			Schemas:     []string{SCIMSchemaGroup},
+			ID:          string(role),
+			DisplayName: string(role),
+			Meta: SCIMMeta{
+				ResourceType: "Group",
+				Location:     groupLocation(role),
+			},
+		}
+
+		if withMembers {
+			group.Members = make([]SCIMMember, 0)
+			for _, m := range members {
+				if m.Role == role {
+					group.Members = append(group.Members, SCIMMember{
+						Value:   strconv.FormatUint(uint64(m.UserID), 10),
+						Display: m.Email,
+						Ref:     userLocation(m.UserID),
+					})
+				}
+			}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (s *SCIMService) ListGroups(orgID uint, filter string, startIndex, count int, withMembers bool) (*SCIMListResponse, error) {
+	comparisons, err := parseSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmp := range comparisons {
+		if cmp.Attr != "displayname" && cmp.Attr != "id" {
+			return nil, scimInvalidFilter("unsupported filter attribute " + cmp.Attr)
+		}
+	}
+
+	groups, err := s.groupResources(orgID, withMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]any, 0, len(groups))
+	for _, group := range groups {
+		ok := true
+		for _, cmp := range comparisons {
+			value := group.DisplayName
+			if cmp.Attr == "id" {
+				value = group.ID
+			}
+			ok = ok && matchSCIM(cmp, value)
+		}
+		if ok {
+			matched = append(matched, group)
+		}
+	}
+
+	offset, limit, start := scimPage(startIndex, count)
+	total := int64(len(matched))
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	if offset+limit < len(matched) {
+		matched = matched[offset : offset+limit]
+	} else {
+		matched = matched[offset:]
+	}
+
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(matched),
+		Resources:    matched,
+	}, nil
+}
+
+func (s *SCIMService) GetGroup(orgID uint, id string) (*SCIMGroupResource, error) {
+	groups, err := s.groupResources(orgID, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].ID == id {
+			return &groups[i], nil
+		}
+	}
+	return nil, errSCIMNotFound
+}
+
+func parseMemberIDs(members []SCIMMember) ([]uint, error) {
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, ok := scimID(m.Value)
+		if !ok {
+			return nil, scimInvalidValue("invalid member value " + m.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// setGroupMembers moves users into or out of the group's role. Leaving a
+// group drops the user back to "member", which is the base role.
+func (s *SCIMService) setGroupMembers(orgID, tokenID uint, role models.OrgRole, add, remove []uint, ip, ua string) error {
+	members, err := s.orgRepo.ListMembers(orgID)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[uint]models.OrgRole, len(members))
+	for _, m := range members {
+		current[m.UserID] = m.Role
+	}
+
+	desired := make(map[uint]models.OrgRole)
+	for _, id := range remove {
+		if current[id] == role && role != models.OrgMember {
+			desired[id] = models.OrgMember
+		}
+	}
+	for _, id := range add {
+		if _, ok := current[id]; !ok {
+			return scimInvalidValue("user " + strconv.FormatUint(uint64(id), 10) + " is not provisioned in this organization")
+		}
+		desired[id] = role
+	}
+
+	owners := 0
+	for id, r := range current {
+		if next, ok := desired[id]; ok {
+			r = next
+		}
+		if r == models.OrgOwner {
+			owners++
+		}
+	}
+	if owners == 0 {
+		return errSCIMLastOwner
+	}
+
+	changed := make(map[string]any)
+	for id, next := range desired {
+		if current[id] == next {
+			continue
+		}
+		if err := s.orgRepo.UpdateMemberRole(orgID, id, next); err != nil {
+			return err
+		}
+		changed[strconv.FormatUint(uint64(id), 10)] = next
+	}
+
+	if len(changed) > 0 {
+		s.audit("SCIM_GROUP_UPDATED", tokenID, orgID, nil, ip, ua, map[string]any{
+			"group":   role,
+			"members": changed,
+		})
+	}
+
+	return nil
+}
+
+func (s *SCIMService) groupMemberIDs(orgID uint, role models.OrgRole) ([]uint, error) {
+	members, err := s.orgRepo.ListMembers(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0)
+	for _, m := range members {
+		if m.Role == role {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// ReplaceGroup implements PUT: the group ends up with exactly the given
+// members. The display name of a group cannot change.
+func (s *SCIMService) ReplaceGroup(orgID, tokenID uint, id string, in SCIMGroupInput, ip, ua string) (*SCIMGroupResource, error) {
+	group, err := s.GetGroup(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.DisplayName != "" && in.DisplayName != group.DisplayName {
+		return nil, &SCIMError{Status: 400, ScimType: "mutability", Detail: "groups are organization roles and cannot be renamed"}
+	}
+
+	add, err := parseMemberIDs(in.Members)
+	if err != nil {
+		return nil, err
+	}
+	remove, err := s.groupMemberIDs(orgID, models.OrgRole(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setGroupMembers(orgID, tokenID, models.OrgRole(id), add, remove, ip, ua); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(orgID, id)
+}
+
+func (s *SCIMService) PatchGroup(orgID, tokenID uint, id string, req SCIMPatchRequest, ip, ua string) (*SCIMGroupResource, error) {
+	if _, err := s.GetGroup(orgID, id); err != nil {
+		return nil, err
+	}
+	if len(req.Operations) == 0 {
+		return nil, scimInvalidValue("Operations is required")
+	}
+
+	role := models.OrgRole(id)
+	var add, remove []uint
+
+	for _, operation := range req.Operations {
+		op := strings.ToLower(operation.Op)
+		path := strings.TrimSpace(operation.Path)
+
+		var members []SCIMMember
+		switch {
+		case path == "":
+			var attrs SCIMGroupInput
+			if err := json.Unmarshal(operation.Value, &attrs); err != nil {
+				return nil, &SCIMError{Status: 400, ScimType: "noTarget", Detail: "value must be an object when path is omitted"}
+			}
+			if attrs.DisplayName != "" && attrs.DisplayName != id {
+				return nil, &SCIMError{Status: 400, ScimType: "mutability", Detail: "groups are organization roles and cannot be renamed"}
+			}
+			members = attrs.Members
+		case strings.EqualFold(path, "members"):
+			if len(operation.Value) > 0 {
+				if err := json.Unmarshal(operation.Value, &members); err != nil {
+					return nil, scimInvalidValue("members must be a list")
+				}
+			}
+		case memberValuePath.MatchString(path):
+			members = []SCIMMember{{Value: memberValuePath.FindStringSubmatch(path)[1]}}
+		case strings.EqualFold(path, "displayName"):
+			return nil, &SCIMError{Status: 400, ScimType: "mutability", Detail: "groups are organization roles and cannot be renamed"}
+		default:
+			return nil, &SCIMError{Status: 400, ScimType: "invalidPath", Detail: "unsupported path " + path}
+		}
+
+		ids, err := parseMemberIDs(members)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "add":
+			add = append(add, ids...)
+		case "remove":
+			if len(ids) == 0 && strings.EqualFold(path, "members") {
+				// removing "members" without a value empties the group
+				if ids, err = s.groupMemberIDs(orgID, role); err != nil {
+					return nil, err
+				}
+			}
+			remove = append(remove, ids...)
+		case "replace":
+			current, err := s.groupMemberIDs(orgID, role)
+			if err != nil {
+				return nil, err
+			}
+			remove = append(remove, current...)
+			add = append(add, ids...)
+		default:
+			return nil, scimInvalidValue("unsupported op " + operation.Op)
+		}
+	}
+
+	if err := s.setGroupMembers(orgID, tokenID, role, add, remove, ip, ua); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(orgID, id)
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+// ServiceAccountService manages non-human principals. They are kept out of
+// the users table so they can never go through password login or reset.
+type ServiceAccountService struct {
+	serviceAccountRepo *repositories.ServiceAccountRepository
+	apiKeyRepo         *repositories.APIKeyRepository
+	apiKeyService      *APIKeyService
+	orgRepo            *repositories.OrganizationRepository
+	permRepo           *repositories.PermissionRepository
+	auditRepo          *repositories.AuditRepo
+	jwtCfg             config.JWTConfig
+}
+
+func NewServiceAccountService(serviceAccountRepo *repositories.ServiceAccountRepository, apiKeyRepo *repositories.APIKeyRepository, apiKeyService *APIKeyService, orgRepo *repositories.OrganizationRepository, permRepo *repositories.PermissionRepository, auditRepo *repositories.AuditRepo, jwtCfg config.JWTConfig) *ServiceAccountService {
+	return &ServiceAccountService{
+		serviceAccountRepo: serviceAccountRepo,
+		apiKeyRepo:         apiKeyRepo,
+		apiKeyService:      apiKeyService,
+		orgRepo:            orgRepo,
+		permRepo:           permRepo,
+		auditRepo:          auditRepo,
+		jwtCfg:             jwtCfg,
+	}
+}
+
+func generateClientSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "sas_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create registers a service account owned by the acting admin and,
+// optionally, an organization. scopes limit what its access tokens may do,
+// as for API keys. The client secret is returned once.
+func (s *ServiceAccountService) Create(actorID uint, name, description string, role models.UserRole, scopes []string, orgID uint, ip, ua string) (*models.ServiceAccount, string, error) {
+	name = strings.TrimSpace(name)
+	if role == "" {
+		role = models.User
+	}
+	if name == "" || (role != models.User && role != models.Admin) {
+		return nil, "", ErrInvalidInput
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, "", ErrInvalidInput
+		}
+	}
+
+	sa := &models.ServiceAccount{
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Role:        role,
+		Scopes:      strings.Join(scopes, " "),
+		OwnerUserID: &actorID,
+	}
+
+	if orgID != 0 {
+		org, err := s.orgRepo.FindByID(orgID)
+		if err != nil {
+			return nil, "", err
+		}
+		if org == nil {
+			return nil, "", ErrNotFound
+		}
+		sa.OrganizationID = &orgID
+	}
+
+	idByte := make([]byte, 12)
+	if _, err := rand.Read(idByte); err != nil {
+		return nil, "", err
+	}
+	sa.ClientID = "sa_" + hex.EncodeToString(idByte)
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.serviceAccountRepo.Create(sa, secret); err != nil {
+		return nil, "", err
+	}
+
+	s.auditRepo.LogWithMetadata("SERVICE_ACCOUNT_CREATED", &actorID, ip, ua, map[string]any{
+		"service_account_id": sa.ID,
+		"role":               sa.Role,
+		"scopes":             sa.ScopeList(),
+		"org_id":             orgID,
+	})
+
+	return sa, secret, nil
+}
+
+// IsActive implements security.ServiceAccountChecker. The lookup is cached
+// and the cache is dropped on disable and delete, so access tokens of the
+// account stop working right away.
+func (s *ServiceAccountService) IsActive(id uint) bool {
+	role, err := s.permRepo.GetServiceAccountRole(context.Background(), id)
+	return err == nil && role != ""
+}
+
+func (s *ServiceAccountService) List(orgID uint) ([]models.ServiceAccount, error) {
+	return s.serviceAccountRepo.List(orgID)
+}
+
+func (s *ServiceAccountService) Get(id uint) (*models.ServiceAccount, error) {
+	sa, err := s.serviceAccountRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sa == nil {
+		return nil, ErrNotFound
+	}
+	return sa, nil
+}
+
+func (s *ServiceAccountService) RotateSecret(actorID, id uint, ip, ua string) (string, error) {
+	if _, err := s.Get(id); err != nil {
+		return "", err
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.serviceAccountRepo.UpdateSecret(id, secret); err != nil {
+		return "", err
+	}
+
+	s.auditRepo.LogWithMetadata("SERVICE_ACCOUNT_SECRET_ROTATED", &actorID, ip, ua, map[string]any{
+		"service_account_id": id,
+	})
+
+	return secret, nil
+}
+
+func (s *ServiceAccountService) SetDisabled(actorID, id uint, disabled bool, ip, ua string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+
+	if err := s.serviceAccountRepo.SetDisabled(id, disabled); err != nil {
+		return err
+	}
+	_ = s.permRepo.InvalidateServiceAccount(context.Background(), id)
+
+	event := "SERVICE_ACCOUNT_ENABLED"
+	if disabled {
+		event = "SERVICE_ACCOUNT_DISABLED"
+	}
+	s.auditRepo.LogWithMetadata(event, &actorID, ip, ua, map[string]any{
+		"service_account_id": id,
+	})
+
+	return nil
+}
+
+func (s *ServiceAccountService) Delete(actorID, id uint, ip, ua string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+
+	if err := s.serviceAccountRepo.Delete(id); err != nil {
+		return err
+	}
+	_ = s.permRepo.InvalidateServiceAccount(context.Background(), id)
+
+	s.auditRepo.LogWithMetadata("SERVICE_ACCOUNT_DELETED", &actorID, ip, ua, map[string]any{
+		"service_account_id": id,
+	})
+
+	return nil
+}
+
+func (s *ServiceAccountService) CreateAPIKey(actorID, id uint, name string, scopes []string, expiresIn time.Duration, ip, ua string) (*models.APIKey, string, error) {
+	sa, err := s.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.apiKeyService.CreateForServiceAccount(sa, actorID, name, scopes, expiresIn, ip, ua)
+}
+
+func (s *ServiceAccountService) ListAPIKeys(id uint) ([]models.APIKey, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+
+	return s.apiKeyRepo.ListByServiceAccount(id)
+}
+
+func (s *ServiceAccountService) RevokeAPIKey(actorID, id, keyID uint, ip, ua string) error {
+	revoked, err := s.apiKeyRepo.RevokeForServiceAccount(id, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNotFound
+	}
+
+	s.auditRepo.LogWithMetadata("SERVICE_ACCOUNT_KEY_REVOKED", &actorID, ip, ua, map[string]any{
+		"service_account_id": id,
+		"key_id":             keyID,
+	})
+
+	return nil
+}
+
+// IssueToken implements the client credentials grant: a valid client ID and
+// secret are exchanged for a short-lived access token marked as a service
+// principal. No refresh token or session is created.
+func (s *ServiceAccountService) IssueToken(clientID, clientSecret, ip, ua string) (*TokenPair, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrInvalidInput
+	}
+
+	sa, err := s.serviceAccountRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if sa == nil {
+		s.auditRepo.Log("SERVICE_TOKEN_FAILED", nil, ip, ua)
+		return nil, ErrInvalidCredentials
+	}
+
+	if sa.DisabledAt != nil || !s.serviceAccountRepo.MatchesSecret(sa, clientSecret) {
+		s.auditRepo.LogServiceAccount("SERVICE_TOKEN_FAILED", sa.ID, ip, ua, nil)
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := security.AccessClaims{
+		Role:             string(sa.Role),
+		PrincipalType:    security.PrincipalService,
+		ServiceAccountID: sa.ID,
+		Scopes:           sa.ScopeList(),
+	}
+	if sa.OrganizationID != nil {
+		claims.OrgID = *sa.OrganizationID
+	}
+
+	accessToken, err := security.SignAccessToken(claims, s.jwtCfg.AccessSecret, s.jwtCfg.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditRepo.LogServiceAccount("SERVICE_TOKEN_ISSUED", sa.ID, ip, ua, nil)
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.jwtCfg.AccessTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"net"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
+)
+
+const (
+	SessionBindingOff    = "off"
+	SessionBindingFlag   = "flag"
+	SessionBindingReject = "reject"
+)
+
+var ErrSessionBinding = errors.New("session used from a different client")
+
+// sessionBinding fingerprints the client: its browser and OS (not their
+// versions, which change with updates) and the subnet of its IP.
+func (s *AuthService) sessionBinding(ip, ua string) repositories.SessionBinding {
+	device := useragent.Parse(ua)
+	return repositories.SessionBinding{
+		UAFamily: device.Browser + "/" + device.OS,
+		IPSubnet: s.ipSubnet(ip),
+	}
+}
+
+func (s *AuthService) ipSubnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if ip4 := parsed.To4(); ip4 != nil {
+		prefix := s.sessionCfg.BindingIPv4Prefix
+		if prefix <= 0 || prefix > 32 {
+			prefix = 32
+		}
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(prefix, 32)), Mask: net.CIDRMask(prefix, 32)}).String()
+	}
+
+	prefix := s.sessionCfg.BindingIPv6Prefix
+	if prefix <= 0 || prefix > 128 {
+		prefix = 128
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(prefix, 128)), Mask: net.CIDRMask(prefix, 128)}).String()
+}
+
+// bindingMatches reports whether current is the client the session is
+// bound to. Sessions without a recorded binding match any client.
+func bindingMatches(bound, current repositories.SessionBinding) bool {
+	if bound.UAFamily == "" && bound.IPSubnet == "" {
+		return true
+	}
+	return bound == current
+}
+
+// checkSessionBinding compares the refreshing client with the one the
+// session is bound to and applies the binding policy. Sessions from before
+// binding was recorded are let through.
+func (s *AuthService) checkSessionBinding(userID uint, session *repositories.SessionInfo, current repositories.SessionBinding, ip, ua string) error {
+	policy := s.sessionCfg.BindingPolicy
+	if policy != SessionBindingFlag && policy != SessionBindingReject {
+		return nil
+	}
+
+	bound := session.Binding
+	if bindingMatches(bound, current) {
+		return nil
+	}
+
+	s.auditRepo.LogWithMetadata("SESSION_BINDING_MISMATCH", &userID, ip, ua, map[string]any{
+		"session_id":    session.SessionID,
+		"policy":        policy,
+		"old_ua_family": bound.UAFamily,
+		"new_ua_family": current.UAFamily,
+		"old_ip_subnet": bound.IPSubnet,
+		"new_ip_subnet": current.IPSubnet,
+	})
+
+	if policy == SessionBindingReject {
+		return ErrSessionBinding
+	}
+	return nil
+}
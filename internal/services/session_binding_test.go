@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/config"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	chromeWindows120 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	chromeWindows121 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	firefoxWindows   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	chromeMac        = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+func TestIPSubnet(t *testing.T) {
+	tests := []struct {
+		name   string
+		v4, v6 int
+		ip     string
+		want   string
+	}{
+		{name: "v4 /24", v4: 24, v6: 64, ip: "203.0.113.77", want: "203.0.113.0/24"},
+		{name: "v4 /16", v4: 16, v6: 64, ip: "203.0.113.77", want: "203.0.0.0/16"},
+		{name: "v4 /32", v4: 32, v6: 64, ip: "203.0.113.77", want: "203.0.113.77/32"},
+		{name: "v4 prefix unset", v4: 0, v6: 64, ip: "203.0.113.77", want: "203.0.113.77/32"},
+		{name: "v4 prefix too long", v4: 40, v6: 64, ip: "203.0.113.77", want: "203.0.113.77/32"},
+		{name: "v4-mapped v6 uses the v4 prefix", v4: 24, v6: 64, ip: "::ffff:203.0.113.77", want: "203.0.113.0/24"},
+		{name: "v6 /64", v4: 24, v6: 64, ip: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1:2::/64"},
+		{name: "v6 /48", v4: 24, v6: 48, ip: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1::/48"},
+		{name: "v6 /128", v4: 24, v6: 128, ip: "2001:db8::1", want: "2001:db8::1/128"},
+		{name: "v6 prefix unset", v4: 24, v6: 0, ip: "2001:db8::1", want: "2001:db8::1/128"},
+		{name: "v6 prefix too long", v4: 24, v6: 200, ip: "2001:db8::1", want: "2001:db8::1/128"},
+		{name: "v6 case and zeros", v4: 24, v6: 64, ip: "2001:0DB8:0001:0002::ABCD", want: "2001:db8:1:2::/64"},
+		{name: "unparsable", v4: 24, v6: 64, ip: "unknown", want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &AuthService{sessionCfg: config.SessionConfig{BindingIPv4Prefix: tt.v4, BindingIPv6Prefix: tt.v6}}
+			if got := s.ipSubnet(tt.ip); got != tt.want {
+				t.Errorf("ipSubnet(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionBindingMatch(t *testing.T) {
+	s := &AuthService{sessionCfg: config.SessionConfig{BindingIPv4Prefix: 24, BindingIPv6Prefix: 64}}
+
+	tests := []struct {
+		name      string
+		boundIP   string
+		boundUA   string
+		currentIP string
+		currentUA string
+		want      bool
+	}{
+		{name: "same client", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.7", currentUA: chromeWindows120, want: true},
+		{name: "browser update", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.7", currentUA: chromeWindows121, want: true},
+		{name: "new address in the v4 subnet", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.250", currentUA: chromeWindows120, want: true},
+		{name: "other v4 subnet", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.114.7", currentUA: chromeWindows120, want: false},
+		{name: "new address in the v6 subnet", boundIP: "2001:db8:1:2::10", boundUA: chromeWindows120, currentIP: "2001:db8:1:2:ffff::1", currentUA: chromeWindows120, want: true},
+		{name: "other v6 subnet", boundIP: "2001:db8:1:2::10", boundUA: chromeWindows120, currentIP: "2001:db8:1:3::10", currentUA: chromeWindows120, want: false},
+		{name: "v4 to v6", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "2001:db8:1:2::10", currentUA: chromeWindows120, want: false},
+		{name: "v4 to its v4-mapped form", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "::ffff:203.0.113.9", currentUA: chromeWindows120, want: true},
+		{name: "other browser", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.7", currentUA: firefoxWindows, want: false},
+		{name: "other os", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.7", currentUA: chromeMac, want: false},
+		{name: "user agent dropped", boundIP: "203.0.113.7", boundUA: chromeWindows120, currentIP: "203.0.113.7", currentUA: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound := s.sessionBinding(tt.boundIP, tt.boundUA)
+			current := s.sessionBinding(tt.currentIP, tt.currentUA)
+			if got := bindingMatches(bound, current); got != tt.want {
+				t.Errorf("bindingMatches(%+v, %+v) = %v, want %v", bound, current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindingMatchesUnboundSession(t *testing.T) {
+	current := repositories.SessionBinding{UAFamily: "Chrome/Windows", IPSubnet: "203.0.113.0/24"}
+	if !bindingMatches(repositories.SessionBinding{}, current) {
+		t.Error("a session without a recorded binding must match any client")
+	}
+}
+
+// TestCheckSessionBindingLetsMatchesThrough covers the paths that write no
+// audit entry, so it runs without a database.
+func TestCheckSessionBindingLetsMatchesThrough(t *testing.T) {
+	bound := repositories.SessionBinding{UAFamily: "Chrome/Windows", IPSubnet: "203.0.113.0/24"}
+	other := repositories.SessionBinding{UAFamily: "Firefox/Linux", IPSubnet: "198.51.100.0/24"}
+
+	tests := []struct {
+		name    string
+		policy  string
+		bound   repositories.SessionBinding
+		current repositories.SessionBinding
+	}{
+		{name: "off ignores a mismatch", policy: SessionBindingOff, bound: bound, current: other},
+		{name: "unknown policy ignores a mismatch", policy: "strict", bound: bound, current: other},
+		{name: "reject lets the same client through", policy: SessionBindingReject, bound: bound, current: bound},
+		{name: "reject lets an unbound session through", policy: SessionBindingReject, current: other},
+		{name: "flag lets the same client through", policy: SessionBindingFlag, bound: bound, current: bound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &AuthService{sessionCfg: config.SessionConfig{BindingPolicy: tt.policy}}
+			session := &repositories.SessionInfo{SessionID: "s1", Binding: tt.bound}
+			if err := s.checkSessionBinding(1, session, tt.current, "203.0.113.7", chromeWindows120); err != nil {
+				t.Errorf("checkSessionBinding = %v, want nil", err)
+			}
+		})
+	}
+}
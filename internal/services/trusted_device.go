@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/middlewares/security"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/useragent"
+	"github.com/google/uuid"
+)
+
+// trustedDeviceFingerprint binds a trusted device token to the browser it
+// was issued to, using the same coarse fingerprint as login alerts.
+func trustedDeviceFingerprint(ua string) string {
+	device := useragent.Parse(ua)
+	sum := sha256.Sum256([]byte(deviceFingerprint(device.Browser, device.OS, device.Device)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// rememberDevice makes the current device trusted for the user and returns
+// the token to store in its cookie. It returns "" when remembering devices
+// is turned off.
+func (s *AuthService) rememberDevice(user *models.UserModel, ip, ua string) (string, error) {
+	ttl := s.jwtCfg.TrustedDeviceTTL
+	if ttl <= 0 {
+		return "", nil
+	}
+
+	deviceID := uuid.NewString()
+	fingerprint := trustedDeviceFingerprint(ua)
+
+	token, err := security.GenerateTrustedDeviceToken(user.ID, deviceID, fingerprint, s.jwtCfg.RefreshSecret, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.trustedDeviceRepo.Create(context.Background(), deviceID, user.ID, fingerprint, ip, ua, ttl); err != nil {
+		return "", err
+	}
+
+	s.auditRepo.LogWithMetadata("TRUSTED_DEVICE_ADDED", &user.ID, ip, ua, map[string]any{
+		"device_id": deviceID,
+	})
+
+	return token, nil
+}
+
+// trustedDeviceID returns the ID of the trusted device behind token if it
+// is still trusted for the user and the login comes from the same kind of
+// browser, or "" otherwise.
+func (s *AuthService) trustedDeviceID(ctx context.Context, user *models.UserModel, token, ip, ua string) string {
+	if token == "" {
+		return ""
+	}
+
+	claims, err := security.ParseTrustedDeviceToken(token, s.jwtCfg.RefreshSecret)
+	if err != nil || claims.UserID != user.ID {
+		return ""
+	}
+
+	fingerprint := trustedDeviceFingerprint(ua)
+	if claims.Fingerprint != fingerprint {
+		return ""
+	}
+
+	device, err := s.trustedDeviceRepo.Get(ctx, claims.DeviceID)
+	if err != nil || device == nil || device.UserID != user.ID || device.Fingerprint != fingerprint {
+		return ""
+	}
+
+	_ = s.trustedDeviceRepo.Touch(ctx, device.DeviceID, ip)
+	return device.DeviceID
+}
+
+func (s *AuthService) ListTrustedDevices(userID uint) ([]repositories.TrustedDevice, error) {
+	return s.trustedDeviceRepo.ListByUser(context.Background(), userID)
+}
+
+// RevokeTrustedDevice makes the next login from the device ask for the
+// second factor again.
+func (s *AuthService) RevokeTrustedDevice(userID uint, deviceID, ip, ua string) error {
+	ctx := context.Background()
+
+	device, err := s.trustedDeviceRepo.Get(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.UserID != userID {
+		return ErrNotFound
+	}
+
+	if err := s.trustedDeviceRepo.Delete(ctx, deviceID, userID); err != nil {
+		return err
+	}
+
+	s.auditRepo.LogWithMetadata("TRUSTED_DEVICE_REVOKED", &userID, ip, ua, map[string]any{
+		"device_id": deviceID,
+	})
+
+	return nil
+}
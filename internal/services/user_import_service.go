@@ -0,0 +1,458 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/models"
+	"github.com/RahulKumar9988/auth-microservices-goFiber/internal/repositories"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	importChunkSize = 500
+	exportBatchSize = 500
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// csvColumns is the export column order. Import matches columns by header
+// name and ignores unknown ones, so an export can be imported back.
+var csvColumns = []string{"id", "email", "role", "status", "must_reset_password", "created_at", "password_hash"}
+
+type ImportRow struct {
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	Status       string `json:"status"`
+	PasswordHash string `json:"password_hash"`
+}
+
+type ImportOptions struct {
+	Format string
+	OrgID  uint
+	DryRun bool
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Invited int              `json:"invited"`
+	Failed  int              `json:"failed"`
+	DryRun  bool             `json:"dry_run"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+func (r *ImportReport) fail(row int, email, msg string) {
+	r.Failed++
+	r.Errors = append(r.Errors, ImportRowError{Row: row, Email: email, Error: msg})
+}
+
+// UserImportService migrates accounts in and out in bulk. Rows carrying a
+// bcrypt or argon2id hash keep their password; rows without one get an
+// unusable password and an account setup token.
+type UserImportService struct {
+	userRepo    *repositories.UserRepository
+	orgRepo     *repositories.OrganizationRepository
+	authService *AuthService
+	auditRepo   *repositories.AuditRepo
+}
+
+func NewUserImportService(userRepo *repositories.UserRepository, orgRepo *repositories.OrganizationRepository, authService *AuthService, auditRepo *repositories.AuditRepo) *UserImportService {
+	return &UserImportService{
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		authService: authService,
+		auditRepo:   auditRepo,
+	}
+}
+
+/* ============================
+   Row decoding
+============================ */
+
+type rowReader interface {
+	// Next returns the next row and its 1-based position in the input, or
+	// io.EOF. A non-EOF error applies to that row only.
+	Next() (ImportRow, int, error)
+}
+
+type csvRowReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVRowReader(in io.Reader) (*csvRowReader, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, ErrInvalidInput
+	}
+
+	return &csvRowReader{r: r, columns: columns}, nil
+}
+
+func (c *csvRowReader) Next() (ImportRow, int, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return ImportRow{}, 0, io.EOF
+	}
+	c.row++
+	if err != nil {
+		return ImportRow{}, c.row, errors.New("malformed csv record")
+	}
+
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	return ImportRow{
+		Email:        field("email"),
+		Role:         field("role"),
+		Status:       field("status"),
+		PasswordHash: field("password_hash"),
+	}, c.row, nil
+}
+
+type ndjsonRowReader struct {
+	dec    *json.Decoder
+	row    int
+	broken bool
+}
+
+func (n *ndjsonRowReader) Next() (ImportRow, int, error) {
+	if n.broken {
+		return ImportRow{}, 0, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := n.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return ImportRow{}, 0, io.EOF
+		}
+		// the stream cannot be resynchronised after a syntax error, so
+		// report it once and stop reading
+		n.broken = true
+		return ImportRow{}, n.row + 1, errors.New("malformed json, import stopped at this row")
+	}
+	n.row++
+
+	var row ImportRow
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return ImportRow{}, n.row, errors.New("malformed json object")
+	}
+	return row, n.row, nil
+}
+
+func newRowReader(format string, in io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVRowReader(in)
+	case FormatNDJSON:
+		return &ndjsonRowReader{dec: json.NewDecoder(in)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+/* ============================
+   Import
+============================ */
+
+type pendingUser struct {
+	row  int
+	user models.UserModel
+}
+
+// validateRow normalises a row into a user, or explains why it is rejected.
+func validateRow(row ImportRow) (models.UserModel, string) {
+	email := strings.TrimSpace(strings.ToLower(row.Email))
+	if email == "" {
+		return models.UserModel{}, "email is required"
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return models.UserModel{}, "invalid email"
+	}
+
+	user := models.UserModel{
+		Email:  email,
+		Role:   models.UserRole(strings.TrimSpace(row.Role)),
+		Status: models.UserStatus(strings.TrimSpace(row.Status)),
+	}
+
+	if user.Role == "" {
+		user.Role = models.User
+	}
+	if user.Role != models.User && user.Role != models.Admin {
+		return models.UserModel{}, "invalid role"
+	}
+
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
+	if user.Status != models.StatusActive && user.Status != models.StatusSuspended {
+		return models.UserModel{}, "invalid status"
+	}
+
+	hash := strings.TrimSpace(row.PasswordHash)
+	if hash == "" {
+		user.Password = unusablePassword
+		user.MustResetPassword = true
+	} else {
+		if !validPasswordHash(hash) {
+			return models.UserModel{}, "unsupported password hash (bcrypt or argon2id within cost limits required)"
+		}
+		user.Password = hash
+	}
+
+	return user, ""
+}
+
+// Import reads users from in and creates them importChunkSize at a time,
+// each chunk in its own transaction. Invalid rows, duplicates and failed
+// chunks are reported per row; the rest of the input is still imported.
+func (s *UserImportService) Import(actorID uint, in io.Reader, opts ImportOptions, ip, ua string) (*ImportReport, error) {
+	if opts.OrgID != 0 {
+		org, err := s.orgRepo.FindByID(opts.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		if org == nil {
+			return nil, ErrNotFound
+		}
+	}
+
+	rows, err := newRowReader(opts.Format, in)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Errors: make([]ImportRowError, 0)}
+	seen := make(map[string]bool)
+	chunk := make([]pendingUser, 0, importChunkSize)
+
+	for {
+		row, n, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		report.Total++
+
+		if err != nil {
+			report.fail(n, "", err.Error())
+			continue
+		}
+
+		user, problem := validateRow(row)
+		if problem != "" {
+			report.fail(n, row.Email, problem)
+			continue
+		}
+		if seen[user.Email] {
+			report.fail(n, user.Email, "duplicate email in input")
+			continue
+		}
+		seen[user.Email] = true
+
+		chunk = append(chunk, pendingUser{row: n, user: user})
+		if len(chunk) == importChunkSize {
+			if err := s.importChunk(chunk, opts, report, ip, ua); err != nil {
+				return nil, err
+			}
+			chunk = chunk[:0]
+		}
+	}
+
+	if err := s.importChunk(chunk, opts, report, ip, ua); err != nil {
+		return nil, err
+	}
+
+	var actor *uint
+	if actorID != 0 {
+		actor = &actorID
+	}
+	s.auditRepo.LogWithMetadata("ADMIN_USERS_IMPORTED", actor, ip, ua, map[string]any{
+		"actor_id": actorID,
+		"org_id":   opts.OrgID,
+		"format":   opts.Format,
+		"dry_run":  opts.DryRun,
+		"total":    report.Total,
+		"created":  report.Created,
+		"invited":  report.Invited,
+		"failed":   report.Failed,
+	})
+
+	return report, nil
+}
+
+func (s *UserImportService) importChunk(chunk []pendingUser, opts ImportOptions, report *ImportReport, ip, ua string) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	emails := make([]string, 0, len(chunk))
+	for _, p := range chunk {
+		emails = append(emails, p.user.Email)
+	}
+
+	existing, err := s.userRepo.ExistingEmails(emails)
+	if err != nil {
+		return err
+	}
+
+	accepted := make([]pendingUser, 0, len(chunk))
+	for _, p := range chunk {
+		if existing[p.user.Email] {
+			report.fail(p.row, p.user.Email, "user already exists")
+			continue
+		}
+		accepted = append(accepted, p)
+	}
+
+	if opts.DryRun {
+		report.Created += len(accepted)
+		return nil
+	}
+
+	users := make([]models.UserModel, 0, len(accepted))
+	for _, p := range accepted {
+		users = append(users, p.user)
+	}
+
+	if err := s.userRepo.CreateBatch(users, opts.OrgID); err != nil {
+		// the whole chunk was rolled back; report every row in it
+		for _, p := range accepted {
+			report.fail(p.row, p.user.Email, "chunk rolled back: "+err.Error())
+		}
+		return nil
+	}
+
+	report.Created += len(users)
+	for i := range users {
+		if !users[i].MustResetPassword {
+			continue
+		}
+		if err := s.authService.SendAccountSetup(&users[i], ip, ua); err != nil {
+			report.fail(accepted[i].row, users[i].Email, "created, but account setup could not be sent")
+			continue
+		}
+		report.Invited++
+	}
+
+	return nil
+}
+
+/* ============================
+   Export
+============================ */
+
+// Export streams users of orgID (every user when orgID is 0) to w. Password
+// hashes are only included when asked for, e.g. to migrate to another
+// deployment; only usertool asks, never the HTTP endpoint.
+func (s *UserImportService) Export(actorID, orgID uint, format string, includeHash bool, w io.Writer, ip, ua string) error {
+	if format != FormatCSV && format != FormatNDJSON {
+		return ErrUnsupportedFormat
+	}
+
+	var actor *uint
+	if actorID != 0 {
+		actor = &actorID
+	}
+	s.auditRepo.LogWithMetadata("ADMIN_USERS_EXPORTED", actor, ip, ua, map[string]any{
+		"actor_id":              actorID,
+		"org_id":                orgID,
+		"format":                format,
+		"include_password_hash": includeHash,
+	})
+
+	if format == FormatNDJSON {
+		enc := json.NewEncoder(w)
+		return s.userRepo.StreamUsers(orgID, exportBatchSize, func(users []models.UserModel) error {
+			for _, u := range users {
+				record := map[string]any{
+					"id":                  u.ID,
+					"email":               u.Email,
+					"role":                u.Role,
+					"status":              u.Status,
+					"must_reset_password": u.MustResetPassword,
+					"created_at":          u.CreatedAt,
+				}
+				if includeHash && u.Password != unusablePassword {
+					record["password_hash"] = u.Password
+				}
+				if err := enc.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	cw := csv.NewWriter(w)
+	columns := csvColumns
+	if !includeHash {
+		columns = columns[:len(columns)-1]
+	}
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	err := s.userRepo.StreamUsers(orgID, exportBatchSize, func(users []models.UserModel) error {
+		for _, u := range users {
+			record := []string{
+				strconv.FormatUint(uint64(u.ID), 10),
+				u.Email,
+				string(u.Role),
+				string(u.Status),
+				strconv.FormatBool(u.MustResetPassword),
+				u.CreatedAt.UTC().Format(time.RFC3339),
+			}
+			if includeHash {
+				hash := u.Password
+				if hash == unusablePassword {
+					hash = ""
+				}
+				record = append(record, hash)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
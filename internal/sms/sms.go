@@ -0,0 +1,104 @@
+// Package sms provides the drivers behind services.SMSSender.
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+/* ============================
+   Log driver
+============================ */
+
+// LogSender writes messages to a file, or stdout when no path is given,
+// instead of sending them. Meant for local development.
+type LogSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewLogSender(path string) *LogSender {
+	return &LogSender{path: path}
+}
+
+func (s *LogSender) Send(ctx context.Context, to, message string) error {
+	line := fmt.Sprintf("%s SMS to=%s message=%q\n", time.Now().UTC().Format(time.RFC3339), to, message)
+
+	if s.path == "" {
+		_, err := io.WriteString(os.Stdout, line)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(line)
+	return err
+}
+
+/* ============================
+   HTTP driver
+============================ */
+
+// HTTPSender posts {"from", "to", "message"} as JSON to a provider endpoint
+// with an optional bearer token. Any 2xx response counts as sent.
+type HTTPSender struct {
+	url    string
+	token  string
+	from   string
+	client *http.Client
+}
+
+func NewHTTPSender(url, token, from string) *HTTPSender {
+	return &HTTPSender{
+		url:    url,
+		token:  token,
+		from:   from,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, to, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"from":    s.from,
+		"to":      to,
+		"message": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sms provider returned %s", resp.Status)
+	}
+	return nil
+}
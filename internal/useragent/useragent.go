@@ -0,0 +1,156 @@
+// Package useragent extracts browser, operating system and device type from
+// User-Agent headers for session and audit records.
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+
+	Unknown = "Unknown"
+)
+
+type Info struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device_type"`
+}
+
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Order matters: most browsers also claim to be the ones they are built
+// on, so Chrome must come after Edge and Opera, and Safari after Chrome.
+var browserRules = []rule{
+	{"Edge", regexp.MustCompile(`(?:Edg|Edge|EdgA|EdgiOS)/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|OPiOS)/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`Opera(?:/| )([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Yandex", regexp.MustCompile(`YaBrowser/([\d.]+)`)},
+	{"Vivaldi", regexp.MustCompile(`Vivaldi/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chromium", regexp.MustCompile(`Chromium/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"Internet Explorer", regexp.MustCompile(`MSIE ([\d.]+)`)},
+	{"Internet Explorer", regexp.MustCompile(`Trident/.*rv:([\d.]+)`)},
+}
+
+var botPattern = regexp.MustCompile(`(?i)bot\b|bot/|crawler|spider|slurp|headless|curl/|wget/|python-requests|python-urllib|go-http-client|java/|okhttp|postmanruntime|httpclient|axios/|node-fetch`)
+
+var (
+	windowsPattern = regexp.MustCompile(`Windows NT ([\d.]+)`)
+	iosPattern     = regexp.MustCompile(`(?:iPhone|CPU) OS ([\d_]+)`)
+	androidPattern = regexp.MustCompile(`Android ([\d.]+)`)
+	macPattern     = regexp.MustCompile(`Mac OS X ([\d_.]+)`)
+)
+
+// Windows NT kernel versions to marketing names. Windows 11 still reports
+// NT 10.0.
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+// Parse never fails; fields it cannot work out are Unknown (or empty for
+// versions).
+func Parse(ua string) Info {
+	info := Info{
+		Browser: Unknown,
+		OS:      Unknown,
+		Device:  DeviceUnknown,
+	}
+
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return info
+	}
+
+	info.OS, info.OSVersion = parseOS(ua)
+
+	for _, r := range browserRules {
+		if m := r.pattern.FindStringSubmatch(ua); m != nil {
+			info.Browser = r.name
+			info.BrowserVersion = m[1]
+			break
+		}
+	}
+
+	info.Device = deviceType(ua, info.OS)
+	return info
+}
+
+func parseOS(ua string) (string, string) {
+	// iOS and Android checks come first: iOS user agents say "like Mac OS
+	// X" and Android ones say "Linux".
+	switch {
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		version := ""
+		if m := iosPattern.FindStringSubmatch(ua); m != nil {
+			version = strings.ReplaceAll(m[1], "_", ".")
+		}
+		return "iOS", version
+	case strings.Contains(ua, "Android"):
+		version := ""
+		if m := androidPattern.FindStringSubmatch(ua); m != nil {
+			version = m[1]
+		}
+		return "Android", version
+	case strings.Contains(ua, "Windows Phone"):
+		return "Windows Phone", ""
+	case strings.Contains(ua, "Windows"):
+		version := ""
+		if m := windowsPattern.FindStringSubmatch(ua); m != nil {
+			version = windowsVersions[m[1]]
+			if version == "" {
+				version = m[1]
+			}
+		}
+		return "Windows", version
+	case strings.Contains(ua, "CrOS"):
+		return "ChromeOS", ""
+	case strings.Contains(ua, "Macintosh") || strings.Contains(ua, "Mac OS X"):
+		version := ""
+		if m := macPattern.FindStringSubmatch(ua); m != nil {
+			version = strings.ReplaceAll(m[1], "_", ".")
+		}
+		return "macOS", version
+	case strings.Contains(ua, "Linux") || strings.Contains(ua, "X11"):
+		return "Linux", ""
+	default:
+		return Unknown, ""
+	}
+}
+
+func deviceType(ua, os string) string {
+	switch {
+	case botPattern.MatchString(ua):
+		return DeviceBot
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet"):
+		return DeviceTablet
+	// Android phones send "Mobile"; Android tablets do not
+	case os == "Android" && !strings.Contains(ua, "Mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "Mobi") || os == "iOS" || os == "Android" || os == "Windows Phone":
+		return DeviceMobile
+	case os != Unknown:
+		return DeviceDesktop
+	default:
+		return DeviceUnknown
+	}
+}
@@ -0,0 +1,175 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Info
+	}{
+		{
+			name: "chrome on windows 10",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name: "chrome on windows 7",
+			ua:   "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "109.0.0.0", OS: "Windows", OSVersion: "7", Device: DeviceDesktop},
+		},
+		{
+			name: "chrome on macos is not safari",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "macOS", OSVersion: "10.15.7", Device: DeviceDesktop},
+		},
+		{
+			name: "safari on macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			want: Info{Browser: "Safari", BrowserVersion: "17.1", OS: "macOS", OSVersion: "10.15.7", Device: DeviceDesktop},
+		},
+		{
+			name: "chrome on linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			name: "firefox on linux",
+			ua:   "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: Info{Browser: "Firefox", BrowserVersion: "121.0", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			name: "firefox on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: Info{Browser: "Firefox", BrowserVersion: "121.0", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name: "edge on windows is not chrome",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want: Info{Browser: "Edge", BrowserVersion: "120.0.2210.91", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name: "opera on windows is not chrome",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 OPR/105.0.0.0",
+			want: Info{Browser: "Opera", BrowserVersion: "105.0.0.0", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name: "yandex on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 YaBrowser/23.11.0.0 Safari/537.36",
+			want: Info{Browser: "Yandex", BrowserVersion: "23.11.0.0", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name: "vivaldi on linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Vivaldi/6.5.3206.48",
+			want: Info{Browser: "Vivaldi", BrowserVersion: "6.5.3206.48", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			name: "internet explorer 11",
+			ua:   "Mozilla/5.0 (Windows NT 6.3; Trident/7.0; rv:11.0) like Gecko",
+			want: Info{Browser: "Internet Explorer", BrowserVersion: "11.0", OS: "Windows", OSVersion: "8.1", Device: DeviceDesktop},
+		},
+		{
+			name: "chrome on chromeos",
+			ua:   "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "ChromeOS", Device: DeviceDesktop},
+		},
+		{
+			name: "safari on iphone is not macos",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1.2 Mobile/15E148 Safari/604.1",
+			want: Info{Browser: "Safari", BrowserVersion: "17.1.2", OS: "iOS", OSVersion: "17.1.2", Device: DeviceMobile},
+		},
+		{
+			name: "chrome on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.6099.119", OS: "iOS", OSVersion: "17.1", Device: DeviceMobile},
+		},
+		{
+			name: "firefox on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/121.0 Mobile/15E148 Safari/605.1.15",
+			want: Info{Browser: "Firefox", BrowserVersion: "121.0", OS: "iOS", OSVersion: "17.1", Device: DeviceMobile},
+		},
+		{
+			name: "safari on ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want: Info{Browser: "Safari", BrowserVersion: "16.6", OS: "iOS", OSVersion: "16.6", Device: DeviceTablet},
+		},
+		{
+			name: "chrome on android phone is not linux",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.6099.144", OS: "Android", OSVersion: "14", Device: DeviceMobile},
+		},
+		{
+			name: "chrome on android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X200) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Android", OSVersion: "13", Device: DeviceTablet},
+		},
+		{
+			name: "samsung internet on android",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			want: Info{Browser: "Samsung Internet", BrowserVersion: "23.0", OS: "Android", OSVersion: "13", Device: DeviceMobile},
+		},
+		{
+			name: "firefox on android",
+			ua:   "Mozilla/5.0 (Android 14; Mobile; rv:121.0) Gecko/121.0 Firefox/121.0",
+			want: Info{Browser: "Firefox", BrowserVersion: "121.0", OS: "Android", OSVersion: "14", Device: DeviceMobile},
+		},
+		{
+			name: "edge on android",
+			ua:   "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36 EdgA/120.0.2210.115",
+			want: Info{Browser: "Edge", BrowserVersion: "120.0.2210.115", OS: "Android", OSVersion: "10", Device: DeviceMobile},
+		},
+		{
+			name: "googlebot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceBot},
+		},
+		{
+			name: "googlebot smartphone",
+			ua:   "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Android", OSVersion: "6.0.1", Device: DeviceBot},
+		},
+		{
+			name: "bingbot",
+			ua:   "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceBot},
+		},
+		{
+			name: "headless chrome",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+			want: Info{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Linux", Device: DeviceBot},
+		},
+		{
+			name: "curl",
+			ua:   "curl/8.4.0",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceBot},
+		},
+		{
+			name: "python requests",
+			ua:   "python-requests/2.31.0",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceBot},
+		},
+		{
+			name: "go http client",
+			ua:   "Go-http-client/1.1",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceBot},
+		},
+		{
+			name: "empty",
+			ua:   "",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceUnknown},
+		},
+		{
+			name: "unrecognised",
+			ua:   "SomeApp",
+			want: Info{Browser: Unknown, OS: Unknown, Device: DeviceUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.ua, got, tt.want)
+			}
+		})
+	}
+}
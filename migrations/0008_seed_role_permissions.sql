@@ -0,0 +1,7 @@
+INSERT INTO role_permissions (role, action, resource) VALUES
+    ('admin', '*', '*'),
+    ('user', 'read', 'profile'),
+    ('user', 'update', 'profile'),
+    ('user', 'read', 'sessions'),
+    ('user', 'delete', 'sessions')
+ON CONFLICT (role, action, resource) DO NOTHING;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships(user_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_org_invitations_organization_id ON org_invitations(organization_id);
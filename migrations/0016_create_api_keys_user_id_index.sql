@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS service_account_id INTEGER REFERENCES service_accounts(id) ON DELETE CASCADE;
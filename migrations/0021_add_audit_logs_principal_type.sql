@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS principal_type VARCHAR(20) NOT NULL DEFAULT 'user';
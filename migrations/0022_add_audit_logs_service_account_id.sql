@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS service_account_id INTEGER REFERENCES service_accounts(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memberships_org_external_id ON memberships(organization_id, external_id) WHERE external_id IS NOT NULL;
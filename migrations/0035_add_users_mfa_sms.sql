@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS mfa_sms_enabled BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS browser VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS os VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS device_type VARCHAR(20);
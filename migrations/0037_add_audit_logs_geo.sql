@@ -0,0 +1,4 @@
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS country VARCHAR(2),
+    ADD COLUMN IF NOT EXISTS city TEXT,
+    ADD COLUMN IF NOT EXISTS asn BIGINT;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS managed_by_org_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE memberships ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;